@@ -0,0 +1,162 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: authz/v1/authz.proto
+
+package v1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Rule declares the permission required to call a method:
+//
+//	rpc GetUser (GetUserRequest) returns (User) {
+//	  option (authz.v1.rule) = { action: "iam:users:get" resource: "iam:users:{id}" };
+//	}
+type Rule struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The action checked, e.g. "iam:users:create".
+	Action string `protobuf:"bytes,1,opt,name=action,proto3" json:"action,omitempty"`
+	// The resource checked, "{field}" placeholders are replaced with the fields of the
+	// request message, nested fields are separated by dots, e.g. "iam:users:{user.id}".
+	Resource      string `protobuf:"bytes,2,opt,name=resource,proto3" json:"resource,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Rule) Reset() {
+	*x = Rule{}
+	mi := &file_authz_v1_authz_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Rule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Rule) ProtoMessage() {}
+
+func (x *Rule) ProtoReflect() protoreflect.Message {
+	mi := &file_authz_v1_authz_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Rule.ProtoReflect.Descriptor instead.
+func (*Rule) Descriptor() ([]byte, []int) {
+	return file_authz_v1_authz_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Rule) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *Rule) GetResource() string {
+	if x != nil {
+		return x.Resource
+	}
+	return ""
+}
+
+var file_authz_v1_authz_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*Rule)(nil),
+		Field:         50100,
+		Name:          "authz.v1.rule",
+		Tag:           "bytes,50100,opt,name=rule",
+		Filename:      "authz/v1/authz.proto",
+	},
+}
+
+// Extension fields to descriptorpb.MethodOptions.
+var (
+	// optional authz.v1.Rule rule = 50100;
+	E_Rule = &file_authz_v1_authz_proto_extTypes[0]
+)
+
+var File_authz_v1_authz_proto protoreflect.FileDescriptor
+
+const file_authz_v1_authz_proto_rawDesc = "" +
+	"\n" +
+	"\x14authz/v1/authz.proto\x12\bauthz.v1\x1a google/protobuf/descriptor.proto\":\n" +
+	"\x04Rule\x12\x16\n" +
+	"\x06action\x18\x01 \x01(\tR\x06action\x12\x1a\n" +
+	"\bresource\x18\x02 \x01(\tR\bresource:D\n" +
+	"\x04rule\x12\x1e.google.protobuf.MethodOptions\x18\xb4\x87\x03 \x01(\v2\x0e.authz.v1.RuleR\x04ruleB/Z-github.com/tx7do/kratos-authz/api/authz/v1;v1b\x06proto3"
+
+var (
+	file_authz_v1_authz_proto_rawDescOnce sync.Once
+	file_authz_v1_authz_proto_rawDescData []byte
+)
+
+func file_authz_v1_authz_proto_rawDescGZIP() []byte {
+	file_authz_v1_authz_proto_rawDescOnce.Do(func() {
+		file_authz_v1_authz_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_authz_v1_authz_proto_rawDesc), len(file_authz_v1_authz_proto_rawDesc)))
+	})
+	return file_authz_v1_authz_proto_rawDescData
+}
+
+var file_authz_v1_authz_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_authz_v1_authz_proto_goTypes = []any{
+	(*Rule)(nil),                       // 0: authz.v1.Rule
+	(*descriptorpb.MethodOptions)(nil), // 1: google.protobuf.MethodOptions
+}
+var file_authz_v1_authz_proto_depIdxs = []int32{
+	1, // 0: authz.v1.rule:extendee -> google.protobuf.MethodOptions
+	0, // 1: authz.v1.rule:type_name -> authz.v1.Rule
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	1, // [1:2] is the sub-list for extension type_name
+	0, // [0:1] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_authz_v1_authz_proto_init() }
+func file_authz_v1_authz_proto_init() {
+	if File_authz_v1_authz_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_authz_v1_authz_proto_rawDesc), len(file_authz_v1_authz_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 1,
+			NumServices:   0,
+		},
+		GoTypes:           file_authz_v1_authz_proto_goTypes,
+		DependencyIndexes: file_authz_v1_authz_proto_depIdxs,
+		MessageInfos:      file_authz_v1_authz_proto_msgTypes,
+		ExtensionInfos:    file_authz_v1_authz_proto_extTypes,
+	}.Build()
+	File_authz_v1_authz_proto = out.File
+	file_authz_v1_authz_proto_goTypes = nil
+	file_authz_v1_authz_proto_depIdxs = nil
+}
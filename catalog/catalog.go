@@ -0,0 +1,58 @@
+// Package catalog lists the permissions exposed by the services, as registered by the
+// code generated by protoc-gen-authz.
+package catalog
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+// Permission is the permission required to call an operation.
+type Permission struct {
+	Service   string          `json:"service"`
+	Operation string          `json:"operation"`
+	Action    engine.Action   `json:"action"`
+	Resource  engine.Resource `json:"resource"`
+}
+
+var (
+	mu          sync.RWMutex
+	permissions = map[string]Permission{}
+)
+
+// Register adds the permissions to the catalog, replacing the ones of the same operations.
+func Register(perms ...Permission) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, p := range perms {
+		permissions[p.Operation] = p
+	}
+}
+
+// Permissions returns every registered permission, sorted by operation.
+func Permissions() []Permission {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	result := make([]Permission, 0, len(permissions))
+	for _, p := range permissions {
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Operation < result[j].Operation
+	})
+
+	return result
+}
+
+// Lookup returns the permission of an operation, such as "/api.user.v1.UserService/GetUser".
+func Lookup(operation string) (Permission, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	p, ok := permissions[operation]
+	return p, ok
+}
@@ -0,0 +1,25 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister(t *testing.T) {
+	Register(
+		Permission{Service: "api.user.v1.UserService", Operation: "/api.user.v1.UserService/UpdateUser", Action: "iam:users:update", Resource: "iam:users:{id}"},
+		Permission{Service: "api.user.v1.UserService", Operation: "/api.user.v1.UserService/GetUser", Action: "iam:users:get", Resource: "iam:users:{id}"},
+	)
+
+	permissions := Permissions()
+	assert.Len(t, permissions, 2)
+	assert.Equal(t, "/api.user.v1.UserService/GetUser", permissions[0].Operation)
+
+	p, ok := Lookup("/api.user.v1.UserService/UpdateUser")
+	assert.True(t, ok)
+	assert.EqualValues(t, "iam:users:update", p.Action)
+
+	_, ok = Lookup("/api.user.v1.UserService/DeleteUser")
+	assert.False(t, ok)
+}
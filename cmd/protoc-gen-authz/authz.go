@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+
+	authzV1 "github.com/tx7do/kratos-authz/api/authz/v1"
+)
+
+const (
+	catalogPackage = protogen.GoImportPath("github.com/tx7do/kratos-authz/catalog")
+
+	allProjects = "~~ALL-PROJECTS~~"
+)
+
+var placeholderRegexp = regexp.MustCompile(`\{[^{}]+\}`)
+
+type methodRule struct {
+	service   *protogen.Service
+	method    *protogen.Method
+	operation string
+	rule      *authzV1.Rule
+}
+
+// generateFile generates the constants and the catalog registration of the methods having an
+// authz.v1.rule option, with a Casbin CSV and an OPA JSON policy skeleton.
+func generateFile(gen *protogen.Plugin, file *protogen.File, role string) error {
+	var rules []methodRule
+	for _, service := range file.Services {
+		for _, method := range service.Methods {
+			rule, ok := proto.GetExtension(method.Desc.Options(), authzV1.E_Rule).(*authzV1.Rule)
+			if !ok || rule == nil {
+				continue
+			}
+			rules = append(rules, methodRule{
+				service:   service,
+				method:    method,
+				operation: fmt.Sprintf("/%s/%s", service.Desc.FullName(), method.Desc.Name()),
+				rule:      rule,
+			})
+		}
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	generateGoFile(gen, file, rules)
+	generateCasbinFile(gen, file, rules, role)
+	return generateOpaFile(gen, file, rules, role)
+}
+
+func generateGoFile(gen *protogen.Plugin, file *protogen.File, rules []methodRule) {
+	g := gen.NewGeneratedFile(file.GeneratedFilenamePrefix+"_authz.pb.go", file.GoImportPath)
+	g.P("// Code generated by protoc-gen-authz. DO NOT EDIT.")
+	g.P("// versions:")
+	g.P("// - protoc-gen-authz ", release)
+	g.P("// - protoc           ", protocVersion(gen))
+	g.P("// source: ", file.Desc.Path())
+	g.P()
+	g.P("package ", file.GoPackageName)
+	g.P()
+
+	g.P("const (")
+	for _, r := range rules {
+		name := r.service.GoName + r.method.GoName
+		g.P("AuthzOperation", name, " = ", strconstLiteral(r.operation))
+		g.P("AuthzAction", name, " = ", strconstLiteral(r.rule.GetAction()))
+		g.P("AuthzResource", name, " = ", strconstLiteral(r.rule.GetResource()))
+	}
+	g.P(")")
+
+	for _, service := range servicesOf(rules) {
+		g.P()
+		g.P("// Register", service.GoName, "AuthzCatalog registers the permissions of the ", service.Desc.FullName(), " service.")
+		g.P("func Register", service.GoName, "AuthzCatalog() {")
+		g.P(g.QualifiedGoIdent(catalogPackage.Ident("Register")), "(")
+		for _, r := range rules {
+			if r.service != service {
+				continue
+			}
+			name := r.service.GoName + r.method.GoName
+			g.P(g.QualifiedGoIdent(catalogPackage.Ident("Permission")), "{")
+			g.P("Service: ", strconstLiteral(string(service.Desc.FullName())), ",")
+			g.P("Operation: AuthzOperation", name, ",")
+			g.P("Action: AuthzAction", name, ",")
+			g.P("Resource: AuthzResource", name, ",")
+			g.P("},")
+		}
+		g.P(")")
+		g.P("}")
+	}
+}
+
+// generateCasbinFile generates policy rules of the restfull_with_role model: p, sub, obj, act, dom.
+func generateCasbinFile(gen *protogen.Plugin, file *protogen.File, rules []methodRule, role string) {
+	g := gen.NewGeneratedFile(file.GeneratedFilenamePrefix+"_authz.csv", "")
+	g.P("# Generated by protoc-gen-authz from ", file.Desc.Path(), ", review before use.")
+	for _, r := range rules {
+		g.P("p, ", role, ", ", skeletonResource(r.rule.GetResource()), ", ", r.rule.GetAction(), ", *")
+	}
+}
+
+// generateOpaFile generates the "policies" document read by the OPA engine, one policy per service.
+func generateOpaFile(gen *protogen.Plugin, file *protogen.File, rules []methodRule, role string) error {
+	policies := map[string]interface{}{}
+	for _, service := range servicesOf(rules) {
+		statements := map[string]interface{}{}
+		for _, r := range rules {
+			if r.service != service {
+				continue
+			}
+			statements[string(r.method.Desc.Name())] = map[string]interface{}{
+				"effect":    "allow",
+				"resources": []string{skeletonResource(r.rule.GetResource())},
+				"actions":   []string{r.rule.GetAction()},
+				"projects":  []string{allProjects},
+			}
+		}
+		policies[string(service.Desc.FullName())] = map[string]interface{}{
+			"name":       string(service.Desc.Name()) + " permissions",
+			"members":    []string{role},
+			"statements": statements,
+		}
+	}
+
+	data, err := json.MarshalIndent(map[string]interface{}{"policies": policies}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	g := gen.NewGeneratedFile(file.GeneratedFilenamePrefix+"_authz.json", "")
+	_, err = g.Write(append(data, '\n'))
+	return err
+}
+
+func servicesOf(rules []methodRule) []*protogen.Service {
+	var services []*protogen.Service
+	for _, r := range rules {
+		if len(services) == 0 || services[len(services)-1] != r.service {
+			services = append(services, r.service)
+		}
+	}
+	return services
+}
+
+// skeletonResource replaces the "{field}" placeholders of a resource with wildcards.
+func skeletonResource(resource string) string {
+	return placeholderRegexp.ReplaceAllString(resource, "*")
+}
+
+func protocVersion(gen *protogen.Plugin) string {
+	v := gen.Request.GetCompilerVersion()
+	if v == nil {
+		return "(unknown)"
+	}
+	var suffix string
+	if s := v.GetSuffix(); s != "" {
+		suffix = "-" + s
+	}
+	return fmt.Sprintf("v%d.%d.%d%s", v.GetMajor(), v.GetMinor(), v.GetPatch(), suffix)
+}
+
+func strconstLiteral(s string) string {
+	return fmt.Sprintf("%q", s)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	authzV1 "github.com/tx7do/kratos-authz/api/authz/v1"
+)
+
+func testRequest() *pluginpb.CodeGeneratorRequest {
+	ruleOptions := func(action, resource string) *descriptorpb.MethodOptions {
+		options := &descriptorpb.MethodOptions{}
+		proto.SetExtension(options, authzV1.E_Rule, &authzV1.Rule{Action: action, Resource: resource})
+		return options
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("user/v1/user.proto"),
+		Package:    proto.String("api.user.v1"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"authz/v1/authz.proto"},
+		Options:    &descriptorpb.FileOptions{GoPackage: proto.String("example.com/api/user/v1;v1")},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("GetUserRequest"),
+				Field: []*descriptorpb.FieldDescriptorProto{{
+					Name:     proto.String("id"),
+					Number:   proto.Int32(1),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_UINT64.Enum(),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					JsonName: proto.String("id"),
+				}},
+			},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("UserService"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{
+					Name:       proto.String("GetUser"),
+					InputType:  proto.String(".api.user.v1.GetUserRequest"),
+					OutputType: proto.String(".api.user.v1.GetUserRequest"),
+					Options:    ruleOptions("iam:users:get", "iam:users:{id}"),
+				},
+				{
+					Name:       proto.String("DeleteUser"),
+					InputType:  proto.String(".api.user.v1.GetUserRequest"),
+					OutputType: proto.String(".api.user.v1.GetUserRequest"),
+					Options:    ruleOptions("iam:users:delete", "iam:users:{id}"),
+				},
+				{
+					Name:       proto.String("Ping"),
+					InputType:  proto.String(".api.user.v1.GetUserRequest"),
+					OutputType: proto.String(".api.user.v1.GetUserRequest"),
+				},
+			},
+		}},
+	}
+
+	return &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{file.GetName()},
+		Parameter:      proto.String("paths=source_relative"),
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto),
+			protodesc.ToFileDescriptorProto(authzV1.File_authz_v1_authz_proto),
+			file,
+		},
+	}
+}
+
+func TestGenerateFile(t *testing.T) {
+	gen, err := protogen.Options{}.New(testRequest())
+	require.NoError(t, err)
+
+	for _, f := range gen.Files {
+		if f.Generate {
+			require.NoError(t, generateFile(gen, f, "role:admin"))
+		}
+	}
+
+	response := gen.Response()
+	require.Nil(t, response.Error)
+
+	files := map[string]string{}
+	for _, f := range response.File {
+		files[f.GetName()] = f.GetContent()
+	}
+	require.Len(t, files, 3)
+
+	code := files["user/v1/user_authz.pb.go"]
+	assert.Regexp(t, `AuthzOperationUserServiceGetUser\s+= "/api.user.v1.UserService/GetUser"`, code)
+	assert.Regexp(t, `AuthzActionUserServiceDeleteUser\s+= "iam:users:delete"`, code)
+	assert.Regexp(t, `AuthzResourceUserServiceGetUser\s+= "iam:users:\{id\}"`, code)
+	assert.Contains(t, code, "func RegisterUserServiceAuthzCatalog() {")
+	assert.Contains(t, code, `catalog "github.com/tx7do/kratos-authz/catalog"`)
+	assert.NotContains(t, code, "Ping")
+
+	csv := strings.Split(strings.TrimSpace(files["user/v1/user_authz.csv"]), "\n")
+	assert.Equal(t, []string{
+		"p, role:admin, iam:users:*, iam:users:get, *",
+		"p, role:admin, iam:users:*, iam:users:delete, *",
+	}, csv[1:])
+
+	var store struct {
+		Policies map[string]struct {
+			Members    []string `json:"members"`
+			Statements map[string]struct {
+				Effect    string   `json:"effect"`
+				Resources []string `json:"resources"`
+				Actions   []string `json:"actions"`
+				Projects  []string `json:"projects"`
+			} `json:"statements"`
+		} `json:"policies"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(files["user/v1/user_authz.json"]), &store))
+	policy := store.Policies["api.user.v1.UserService"]
+	assert.Equal(t, []string{"role:admin"}, policy.Members)
+	assert.Len(t, policy.Statements, 2)
+	assert.Equal(t, []string{"iam:users:get"}, policy.Statements["GetUser"].Actions)
+	assert.Equal(t, []string{"iam:users:*"}, policy.Statements["GetUser"].Resources)
+	assert.Equal(t, []string{allProjects}, policy.Statements["GetUser"].Projects)
+}
@@ -0,0 +1,17 @@
+module github.com/tx7do/kratos-authz/cmd/protoc-gen-authz
+
+go 1.25.0
+
+replace github.com/tx7do/kratos-authz => ../../
+
+require (
+	github.com/stretchr/testify v1.11.1
+	github.com/tx7do/kratos-authz v1.1.8
+	google.golang.org/protobuf v1.36.11
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,4 @@
+package main
+
+// release is the current protoc-gen-authz version.
+const release = "v1.0.0"
@@ -0,0 +1,78 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/transport"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+// Record is the audit trail entry of a single authorization check.
+type Record struct {
+	// ID identifies the authorization the record belongs to, it is shared by the checks of a request.
+	ID   string    `json:"id,omitempty"`
+	Time time.Time `json:"time"`
+
+	Subject engine.Subject `json:"subject"`
+	// Subjects is set instead of Subject for the checks of several subjects at once.
+	Subjects engine.Subjects `json:"subjects,omitempty"`
+	Action   engine.Action   `json:"action"`
+	Resource engine.Resource `json:"resource"`
+	Project  engine.Project  `json:"project,omitempty"`
+
+	Effect          engine.Effect `json:"effect"`
+	Engine          string        `json:"engine,omitempty"`
+	MatchedPolicies []string      `json:"matched_policies,omitempty"`
+	Reason          string        `json:"reason,omitempty"`
+	// Error is set when the check failed, Effect is then deny.
+	Error   string        `json:"error,omitempty"`
+	Latency time.Duration `json:"latency"`
+	// Skipped is set for the requests excluded from the authorization, Effect is then allow.
+	Skipped bool `json:"skipped,omitempty"`
+
+	// Operation and Endpoint of the Kratos transport the check has been done for.
+	Operation string `json:"operation,omitempty"`
+	Endpoint  string `json:"endpoint,omitempty"`
+}
+
+func (r *Record) Allowed() bool {
+	return r.Effect == engine.EffectAllow
+}
+
+// Auditor records the authorization checks.
+type Auditor interface {
+	Audit(ctx context.Context, record *Record) error
+}
+
+// NewRecord makes the record of a check, taking the operation and endpoint from the server transport in ctx.
+func NewRecord(ctx context.Context, request engine.Request, decision *engine.Decision, err error, latency time.Duration) *Record {
+	r := &Record{
+		Time:     time.Now(),
+		Subject:  request.Subject,
+		Action:   request.Action,
+		Resource: request.Resource,
+		Project:  request.Project,
+		Effect:   engine.EffectDeny,
+		Latency:  latency,
+	}
+
+	if decision != nil {
+		r.Effect = decision.Effect
+		r.Engine = decision.Engine
+		r.MatchedPolicies = decision.MatchedPolicies
+		r.Reason = decision.Reason
+	}
+	if err != nil {
+		r.Effect = engine.EffectDeny
+		r.Error = err.Error()
+	}
+
+	if tr, ok := transport.FromServerContext(ctx); ok {
+		r.Operation = tr.Operation()
+		r.Endpoint = tr.Endpoint()
+	}
+
+	return r
+}
@@ -0,0 +1,192 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tx7do/kratos-authz/engine"
+	"github.com/tx7do/kratos-authz/engine/noop"
+)
+
+type testTransport struct {
+	transport.Transporter
+}
+
+func (tr *testTransport) Kind() transport.Kind {
+	return transport.KindHTTP
+}
+
+func (tr *testTransport) Endpoint() string {
+	return "http://127.0.0.1:8000"
+}
+
+func (tr *testTransport) Operation() string {
+	return "/api.user.v1.UserService/GetUser"
+}
+
+func TestAuthorizer(t *testing.T) {
+	e, _ := noop.NewEngine(t.Context())
+	ring := NewRingBuffer(10)
+	a := NewAuthorizer(e, ring)
+
+	ctx := transport.NewServerContext(t.Context(), &testTransport{})
+
+	allowed, err := a.IsAuthorized(ctx, "bobo", "GET", "/api/users", "project1")
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+
+	_, err = a.BatchIsAuthorized(ctx, engine.MakeRequests(
+		engine.MakeRequest("alice", "GET", "/api/users", ""),
+		engine.MakeRequest("alice", "DELETE", "/api/users", ""),
+	))
+	assert.Nil(t, err)
+
+	records := ring.Records()
+	require.Len(t, records, 3)
+
+	r := records[0]
+	assert.Equal(t, engine.Subject("bobo"), r.Subject)
+	assert.Equal(t, engine.Action("GET"), r.Action)
+	assert.Equal(t, engine.Resource("/api/users"), r.Resource)
+	assert.Equal(t, engine.Project("project1"), r.Project)
+	assert.Equal(t, engine.EffectAllow, r.Effect)
+	assert.Equal(t, e.Name(), r.Engine)
+	assert.Equal(t, "/api.user.v1.UserService/GetUser", r.Operation)
+	assert.Equal(t, "http://127.0.0.1:8000", r.Endpoint)
+
+	assert.Equal(t, engine.Action("DELETE"), records[2].Action)
+}
+
+func TestAuthorizer_Filters(t *testing.T) {
+	e, _ := noop.NewEngine(t.Context())
+	ring := NewRingBuffer(10)
+	a := NewAuthorizer(e, ring)
+
+	subjects := engine.MakeSubjects("bobo", "admin")
+
+	_, err := a.ProjectsAuthorized(t.Context(), subjects, "GET", "/api/users", engine.MakeProjects("project1", "project2"))
+	assert.Nil(t, err)
+	_, err = a.FilterAuthorizedPairs(t.Context(), subjects, engine.Pairs{engine.MakePair("/api/users", "GET")})
+	assert.Nil(t, err)
+	_, err = a.FilterAuthorizedProjects(t.Context(), subjects)
+	assert.Nil(t, err)
+
+	records := ring.Records()
+	require.Len(t, records, 4)
+	for _, r := range records {
+		assert.Equal(t, subjects, r.Subjects)
+		assert.Equal(t, engine.EffectDeny, r.Effect)
+	}
+	assert.Equal(t, engine.Project("project1"), records[0].Project)
+	assert.Equal(t, engine.Project("project2"), records[1].Project)
+	assert.Equal(t, engine.Action("GET"), records[2].Action)
+	assert.Equal(t, engine.Resource("/api/users"), records[2].Resource)
+	assert.Equal(t, engine.Project(""), records[3].Project)
+}
+
+// shortBatchAuthorizer answers a single request of every batch.
+type shortBatchAuthorizer struct {
+	engine.Authorizer
+}
+
+func (a *shortBatchAuthorizer) BatchIsAuthorized(_ context.Context, _ engine.Requests) ([]bool, error) {
+	return []bool{true}, nil
+}
+
+func TestAuthorizer_BatchResult(t *testing.T) {
+	e, _ := noop.NewEngine(t.Context())
+	ring := NewRingBuffer(10)
+	a := NewAuthorizer(&shortBatchAuthorizer{Authorizer: e}, ring)
+
+	result, err := a.BatchIsAuthorized(t.Context(), engine.MakeRequests(
+		engine.MakeRequest("alice", "GET", "/api/users", ""),
+		engine.MakeRequest("alice", "DELETE", "/api/users", ""),
+	))
+	assert.ErrorIs(t, err, ErrBatchResult)
+	assert.Nil(t, result)
+
+	records := ring.Records()
+	require.Len(t, records, 2)
+	for _, r := range records {
+		assert.NotEmpty(t, r.Error)
+	}
+}
+
+func TestRingBuffer(t *testing.T) {
+	ring := NewRingBuffer(2)
+	for _, s := range []engine.Subject{"a", "b", "c"} {
+		assert.Nil(t, ring.Audit(t.Context(), &Record{Subject: s}))
+	}
+
+	records := ring.Records()
+	require.Len(t, records, 2)
+	assert.Equal(t, engine.Subject("b"), records[0].Subject)
+	assert.Equal(t, engine.Subject("c"), records[1].Subject)
+
+	ring.Reset()
+	assert.Equal(t, 0, ring.Len())
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	line, _ := json.Marshal(&Record{Subject: "a"})
+	f, err := NewFile(path, WithMaxSize(int64(len(line)+1)*2), WithMaxBackups(1))
+	require.NoError(t, err)
+
+	for _, s := range []engine.Subject{"a", "b", "c", "d", "e"} {
+		require.NoError(t, f.Audit(t.Context(), &Record{Subject: s}))
+	}
+	require.NoError(t, f.Close())
+
+	assert.Equal(t, []engine.Subject{"e"}, readSubjects(t, path))
+	assert.Equal(t, []engine.Subject{"c", "d"}, readSubjects(t, path+".1"))
+	assert.NoFileExists(t, path+".2")
+}
+
+func TestFile_RotationFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	line, _ := json.Marshal(&Record{Subject: "a"})
+	f, err := NewFile(path, WithMaxSize(int64(len(line)+1)), WithMaxBackups(1))
+	require.NoError(t, err)
+	defer f.Close()
+
+	// a directory in place of the backup makes the rotation fail
+	require.NoError(t, os.MkdirAll(filepath.Join(path+".1", "blocked"), 0o700))
+
+	require.NoError(t, f.Audit(t.Context(), &Record{Subject: "a"}))
+	assert.Error(t, f.Audit(t.Context(), &Record{Subject: "b"}))
+	assert.Equal(t, []engine.Subject{"a"}, readSubjects(t, path))
+
+	// the next rotation succeeds once the backup can be replaced
+	require.NoError(t, os.RemoveAll(path+".1"))
+	require.NoError(t, f.Audit(t.Context(), &Record{Subject: "c"}))
+	require.NoError(t, f.Close())
+
+	assert.Equal(t, []engine.Subject{"c"}, readSubjects(t, path))
+	assert.Equal(t, []engine.Subject{"a"}, readSubjects(t, path+".1"))
+}
+
+func readSubjects(t *testing.T, path string) []engine.Subject {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var subjects []engine.Subject
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var r Record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &r))
+		subjects = append(subjects, r.Subject)
+	}
+	return subjects
+}
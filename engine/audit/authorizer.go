@@ -0,0 +1,193 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+var ErrNotWriter = errors.New("authz audit: wrapped authorizer is not an engine.Writer")
+
+var ErrBatchResult = errors.New("authz audit: wrapped authorizer did not answer every request of the batch")
+
+var _ engine.Engine = (*Authorizer)(nil)
+
+// Authorizer records every check of another engine.Authorizer. IsAuthorized is answered with Decide, so the matched policies are recorded too.
+// Failures of the auditor are logged, they do not change the outcome of the check.
+type Authorizer struct {
+	authorizer engine.Authorizer
+	auditor    Auditor
+
+	log *log.Helper
+}
+
+func NewAuthorizer(authorizer engine.Authorizer, auditor Auditor, opts ...OptFunc) *Authorizer {
+	a := &Authorizer{
+		authorizer: authorizer,
+		auditor:    auditor,
+		log:        log.NewHelper(log.With(log.DefaultLogger, "module", "audit.authz.engine")),
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+func (a *Authorizer) Name() string {
+	return a.authorizer.Name()
+}
+
+// ProjectsAuthorized records one entry per requested project, or a single entry
+// without a project when none is requested.
+func (a *Authorizer) ProjectsAuthorized(ctx context.Context, subjects engine.Subjects, action engine.Action, resource engine.Resource, projects engine.Projects) (engine.Projects, error) {
+	start := time.Now()
+	result, err := a.authorizer.ProjectsAuthorized(ctx, subjects, action, resource, projects)
+	latency := time.Since(start)
+
+	if len(projects) == 0 {
+		a.auditSubjects(ctx, subjects, engine.MakeRequest("", action, resource, ""), len(result) > 0, err, latency)
+		return result, err
+	}
+	for _, project := range projects {
+		a.auditSubjects(ctx, subjects, engine.MakeRequest("", action, resource, project), slices.Contains(result, project), err, latency)
+	}
+
+	return result, err
+}
+
+// FilterAuthorizedPairs records one entry per requested pair.
+func (a *Authorizer) FilterAuthorizedPairs(ctx context.Context, subjects engine.Subjects, pairs engine.Pairs) (engine.Pairs, error) {
+	start := time.Now()
+	result, err := a.authorizer.FilterAuthorizedPairs(ctx, subjects, pairs)
+	latency := time.Since(start)
+
+	for _, pair := range pairs {
+		a.auditSubjects(ctx, subjects, engine.MakeRequest("", pair.Action, pair.Resource, ""), slices.Contains(result, pair), err, latency)
+	}
+
+	return result, err
+}
+
+// FilterAuthorizedProjects records one allowed entry per authorized project, or a single
+// denied entry without a project when none is.
+func (a *Authorizer) FilterAuthorizedProjects(ctx context.Context, subjects engine.Subjects) (engine.Projects, error) {
+	start := time.Now()
+	result, err := a.authorizer.FilterAuthorizedProjects(ctx, subjects)
+	latency := time.Since(start)
+
+	if err != nil || len(result) == 0 {
+		a.auditSubjects(ctx, subjects, engine.Request{}, false, err, latency)
+		return result, err
+	}
+	for _, project := range result {
+		a.auditSubjects(ctx, subjects, engine.Request{Project: project}, true, nil, latency)
+	}
+
+	return result, err
+}
+
+func (a *Authorizer) IsAuthorized(ctx context.Context, subject engine.Subject, action engine.Action, resource engine.Resource, project engine.Project) (bool, error) {
+	decision, err := a.Decide(ctx, subject, action, resource, project)
+	if err != nil {
+		return false, err
+	}
+	return decision.Allowed(), nil
+}
+
+func (a *Authorizer) Decide(ctx context.Context, subject engine.Subject, action engine.Action, resource engine.Resource, project engine.Project) (*engine.Decision, error) {
+	start := time.Now()
+	decision, err := a.authorizer.Decide(ctx, subject, action, resource, project)
+	a.audit(ctx, NewRecord(ctx, engine.MakeRequest(subject, action, resource, project), decision, err, time.Since(start)))
+	return decision, err
+}
+
+// BatchIsAuthorized records one entry per request, each with the latency of the whole batch.
+func (a *Authorizer) BatchIsAuthorized(ctx context.Context, requests engine.Requests) ([]bool, error) {
+	start := time.Now()
+	result, err := a.authorizer.BatchIsAuthorized(ctx, requests)
+	latency := time.Since(start)
+	if err == nil && len(result) != len(requests) {
+		result, err = nil, fmt.Errorf("%w: %d results for %d requests", ErrBatchResult, len(result), len(requests))
+	}
+
+	for i, r := range requests {
+		var decision *engine.Decision
+		if err == nil {
+			decision = batchDecision(a.authorizer.Name(), result[i])
+		}
+		a.audit(ctx, NewRecord(ctx, r, decision, err, latency))
+	}
+
+	return result, err
+}
+
+func (a *Authorizer) SetPolicies(ctx context.Context, policies engine.PolicyMap, roles engine.RoleMap) error {
+	return a.write(func(writer engine.Writer) error {
+		return writer.SetPolicies(ctx, policies, roles)
+	})
+}
+
+func (a *Authorizer) AddPolicies(ctx context.Context, policies engine.Policies) error {
+	return a.write(func(writer engine.Writer) error {
+		return writer.AddPolicies(ctx, policies)
+	})
+}
+
+func (a *Authorizer) RemovePolicies(ctx context.Context, policies engine.Policies) error {
+	return a.write(func(writer engine.Writer) error {
+		return writer.RemovePolicies(ctx, policies)
+	})
+}
+
+func (a *Authorizer) AddRoleBindings(ctx context.Context, bindings engine.RoleBindings) error {
+	return a.write(func(writer engine.Writer) error {
+		return writer.AddRoleBindings(ctx, bindings)
+	})
+}
+
+func (a *Authorizer) RemoveRoleBindings(ctx context.Context, bindings engine.RoleBindings) error {
+	return a.write(func(writer engine.Writer) error {
+		return writer.RemoveRoleBindings(ctx, bindings)
+	})
+}
+
+func (a *Authorizer) write(fn func(writer engine.Writer) error) error {
+	writer, ok := a.authorizer.(engine.Writer)
+	if !ok {
+		return ErrNotWriter
+	}
+	return fn(writer)
+}
+
+func (a *Authorizer) audit(ctx context.Context, record *Record) {
+	if err := a.auditor.Audit(ctx, record); err != nil {
+		a.log.Errorf("failed to audit authorization check: %v", err)
+	}
+}
+
+// auditSubjects records a check of several subjects at once.
+func (a *Authorizer) auditSubjects(ctx context.Context, subjects engine.Subjects, request engine.Request, allowed bool, err error, latency time.Duration) {
+	var decision *engine.Decision
+	if err == nil {
+		decision = batchDecision(a.authorizer.Name(), allowed)
+	}
+
+	record := NewRecord(ctx, request, decision, err, latency)
+	record.Subjects = subjects
+	a.audit(ctx, record)
+}
+
+func batchDecision(engineName string, allowed bool) *engine.Decision {
+	if allowed {
+		return engine.MakeAllowDecision(engineName)
+	}
+	return engine.MakeDenyDecision(engineName, "denied")
+}
@@ -0,0 +1,184 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+const (
+	DefaultMaxSize    = 100 << 20
+	DefaultMaxBackups = 5
+)
+
+var _ Auditor = (*File)(nil)
+
+// File appends the records as JSON lines to a file.
+//
+// Once the file would grow past its maximum size it is rotated: the file is renamed
+// to <path>.1, the previous backups are shifted to <path>.2, <path>.3, ... and the
+// backups past the maximum count are removed.
+type File struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+type FileOption func(*File)
+
+// WithMaxSize sets the size in bytes past which the file is rotated, 0 disables the rotation.
+func WithMaxSize(size int64) FileOption {
+	return func(f *File) {
+		f.maxSize = size
+	}
+}
+
+// WithMaxBackups sets how many rotated files are kept.
+func WithMaxBackups(count int) FileOption {
+	return func(f *File) {
+		f.maxBackups = count
+	}
+}
+
+func NewFile(path string, opts ...FileOption) (*File, error) {
+	f := &File{
+		path:       path,
+		maxSize:    DefaultMaxSize,
+		maxBackups: DefaultMaxBackups,
+	}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *File) Audit(_ context.Context, record *Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return fs.ErrClosed
+	}
+
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(line)) > f.maxSize {
+		if err = f.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := f.file.Write(line)
+	f.size += int64(n)
+
+	return err
+}
+
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+
+	err := f.file.Close()
+	f.file = nil
+
+	return err
+}
+
+func (f *File) open() error {
+	file, size, err := openFile(f.path, 0)
+	if err != nil {
+		return err
+	}
+
+	f.file = file
+	f.size = size
+
+	return nil
+}
+
+// rotate moves the current file aside and opens a new one. The current file is
+// only swapped out once the new one is open, so a failed rotation keeps auditing
+// to the current file and the next one is retried.
+func (f *File) rotate() error {
+	if f.maxBackups <= 0 {
+		file, size, err := openFile(f.path, os.O_TRUNC)
+		if err != nil {
+			return err
+		}
+		f.swap(file, size)
+		return nil
+	}
+
+	if err := os.Remove(f.backup(f.maxBackups)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for i := f.maxBackups - 1; i > 0; i-- {
+		if err := os.Rename(f.backup(i), f.backup(i+1)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(f.path, f.backup(1)); err != nil {
+		return err
+	}
+
+	file, size, err := openFile(f.path, os.O_EXCL)
+	if err != nil {
+		// put the current file back, its records are appended as before
+		if rerr := os.Rename(f.backup(1), f.path); rerr != nil {
+			return errors.Join(err, rerr)
+		}
+		return err
+	}
+
+	f.swap(file, size)
+
+	return nil
+}
+
+func (f *File) swap(file *os.File, size int64) {
+	// every record has already been written, the current file has nothing left to flush
+	_ = f.file.Close()
+	f.file = file
+	f.size = size
+}
+
+func (f *File) backup(index int) string {
+	return fmt.Sprintf("%s.%d", f.path, index)
+}
+
+func openFile(path string, flag int) (*os.File, int64, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND|flag, 0o600)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, 0, err
+	}
+
+	return file, info.Size(), nil
+}
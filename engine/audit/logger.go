@@ -0,0 +1,43 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+var _ Auditor = (*Logger)(nil)
+
+// Logger writes the records to a Kratos logger, allowed checks at info level and denied ones at warn level.
+type Logger struct {
+	logger log.Logger
+}
+
+func NewLogger(logger log.Logger) *Logger {
+	return &Logger{logger: log.With(logger, "module", "audit.authz")}
+}
+
+func (l *Logger) Audit(_ context.Context, r *Record) error {
+	level := log.LevelInfo
+	if !r.Allowed() {
+		level = log.LevelWarn
+	}
+
+	return l.logger.Log(level,
+		"id", r.ID,
+		"subject", r.Subject,
+		"subjects", r.Subjects,
+		"action", r.Action,
+		"resource", r.Resource,
+		"project", r.Project,
+		"effect", r.Effect,
+		"engine", r.Engine,
+		"matched_policies", r.MatchedPolicies,
+		"reason", r.Reason,
+		"error", r.Error,
+		"latency", r.Latency.String(),
+		"skipped", r.Skipped,
+		"operation", r.Operation,
+		"endpoint", r.Endpoint,
+	)
+}
@@ -0,0 +1,13 @@
+package audit
+
+import (
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+type OptFunc func(*Authorizer)
+
+func WithLogger(logger log.Logger) OptFunc {
+	return func(a *Authorizer) {
+		a.log = log.NewHelper(log.With(logger, "module", "audit.authz.engine"))
+	}
+}
@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"context"
+	"sync"
+)
+
+var _ Auditor = (*RingBuffer)(nil)
+
+// RingBuffer keeps the latest records in memory, mostly meant for tests.
+type RingBuffer struct {
+	mu      sync.Mutex
+	records []*Record
+	next    int
+	full    bool
+}
+
+func NewRingBuffer(size int) *RingBuffer {
+	if size <= 0 {
+		size = 1
+	}
+	return &RingBuffer{records: make([]*Record, size)}
+}
+
+func (b *RingBuffer) Audit(_ context.Context, record *Record) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.records[b.next] = record
+	b.next = (b.next + 1) % len(b.records)
+	if b.next == 0 {
+		b.full = true
+	}
+
+	return nil
+}
+
+// Records returns the kept records, oldest first.
+func (b *RingBuffer) Records() []*Record {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.full {
+		return append([]*Record{}, b.records[:b.next]...)
+	}
+	return append(append([]*Record{}, b.records[b.next:]...), b.records[:b.next]...)
+}
+
+func (b *RingBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.full {
+		return len(b.records)
+	}
+	return b.next
+}
+
+// Reset drops every kept record.
+func (b *RingBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	clear(b.records)
+	b.next = 0
+	b.full = false
+}
@@ -0,0 +1,262 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+const (
+	keySeparator   = "\x00"
+	listSeparator  = "\x01"
+	fieldSeparator = "\x02"
+)
+
+var ErrNotWriter = errors.New("authz cache: wrapped authorizer is not an engine.Writer")
+
+var _ engine.Engine = (*Authorizer)(nil)
+
+// Authorizer memoizes the results of another engine.Authorizer.
+//
+// Results are kept in a size bounded LRU for a limited time, concurrent identical checks
+// are deduplicated, and every cached result is dropped when the policies are changed
+// through SetPolicies.
+type Authorizer struct {
+	authorizer engine.Authorizer
+
+	ttl        time.Duration
+	maxEntries int
+
+	cache      *lru
+	group      singleflight.Group
+	generation atomic.Uint64
+
+	now func() time.Time
+}
+
+func NewAuthorizer(authorizer engine.Authorizer, opts ...OptFunc) *Authorizer {
+	a := &Authorizer{
+		authorizer: authorizer,
+		ttl:        DefaultTTL,
+		maxEntries: DefaultMaxEntries,
+		now:        time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	a.cache = newLRU(a.maxEntries)
+
+	return a
+}
+
+func (a *Authorizer) Name() string {
+	return a.authorizer.Name()
+}
+
+func (a *Authorizer) ProjectsAuthorized(ctx context.Context, subjects engine.Subjects, action engine.Action, resource engine.Resource, projects engine.Projects) (engine.Projects, error) {
+	key := makeKey("ProjectsAuthorized", subjectsKey(subjects), string(action), string(resource), projectsKey(projects))
+	result, err := load(a, key, func() (engine.Projects, error) {
+		return a.authorizer.ProjectsAuthorized(ctx, subjects, action, resource, projects)
+	})
+	return slices.Clone(result), err
+}
+
+func (a *Authorizer) FilterAuthorizedPairs(ctx context.Context, subjects engine.Subjects, pairs engine.Pairs) (engine.Pairs, error) {
+	key := makeKey("FilterAuthorizedPairs", subjectsKey(subjects), pairsKey(pairs))
+	result, err := load(a, key, func() (engine.Pairs, error) {
+		return a.authorizer.FilterAuthorizedPairs(ctx, subjects, pairs)
+	})
+	return slices.Clone(result), err
+}
+
+func (a *Authorizer) FilterAuthorizedProjects(ctx context.Context, subjects engine.Subjects) (engine.Projects, error) {
+	key := makeKey("FilterAuthorizedProjects", subjectsKey(subjects))
+	result, err := load(a, key, func() (engine.Projects, error) {
+		return a.authorizer.FilterAuthorizedProjects(ctx, subjects)
+	})
+	return slices.Clone(result), err
+}
+
+func (a *Authorizer) IsAuthorized(ctx context.Context, subject engine.Subject, action engine.Action, resource engine.Resource, project engine.Project) (bool, error) {
+	return load(a, isAuthorizedKey(engine.MakeRequest(subject, action, resource, project)), func() (bool, error) {
+		return a.authorizer.IsAuthorized(ctx, subject, action, resource, project)
+	})
+}
+
+func (a *Authorizer) Decide(ctx context.Context, subject engine.Subject, action engine.Action, resource engine.Resource, project engine.Project) (*engine.Decision, error) {
+	key := makeKey("Decide", string(subject), string(action), string(resource), string(project))
+	result, err := load(a, key, func() (*engine.Decision, error) {
+		return a.authorizer.Decide(ctx, subject, action, resource, project)
+	})
+	if err != nil || result == nil {
+		return result, err
+	}
+
+	decision := *result
+	return &decision, nil
+}
+
+// BatchIsAuthorized answers the cached requests directly and sends the remaining ones to the
+// wrapped authorizer in a single batch.
+func (a *Authorizer) BatchIsAuthorized(ctx context.Context, requests engine.Requests) ([]bool, error) {
+	gen := a.generation.Load()
+	now := a.now()
+
+	result := make([]bool, len(requests))
+	var (
+		missed  engine.Requests
+		indices []int
+	)
+	for i, r := range requests {
+		if v, ok := a.cache.get(generationKey(gen, isAuthorizedKey(r)), now); ok {
+			result[i] = v.(bool)
+			continue
+		}
+		missed = append(missed, r)
+		indices = append(indices, i)
+	}
+
+	if len(missed) == 0 {
+		return result, nil
+	}
+
+	allowed, err := a.authorizer.BatchIsAuthorized(ctx, missed)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := a.now().Add(a.ttl)
+	for i, r := range missed {
+		result[indices[i]] = allowed[i]
+		if a.generation.Load() == gen {
+			a.cache.add(generationKey(gen, isAuthorizedKey(r)), allowed[i], expiresAt)
+		}
+	}
+
+	return result, nil
+}
+
+// SetPolicies forwards the policies to the wrapped authorizer and invalidates every cached result.
+func (a *Authorizer) SetPolicies(ctx context.Context, policies engine.PolicyMap, roles engine.RoleMap) error {
+	return a.write(func(writer engine.Writer) error {
+		return writer.SetPolicies(ctx, policies, roles)
+	})
+}
+
+// AddPolicies forwards the policies to the wrapped authorizer and invalidates every cached result.
+func (a *Authorizer) AddPolicies(ctx context.Context, policies engine.Policies) error {
+	return a.write(func(writer engine.Writer) error {
+		return writer.AddPolicies(ctx, policies)
+	})
+}
+
+// RemovePolicies forwards the policies to the wrapped authorizer and invalidates every cached result.
+func (a *Authorizer) RemovePolicies(ctx context.Context, policies engine.Policies) error {
+	return a.write(func(writer engine.Writer) error {
+		return writer.RemovePolicies(ctx, policies)
+	})
+}
+
+// AddRoleBindings forwards the bindings to the wrapped authorizer and invalidates every cached result.
+func (a *Authorizer) AddRoleBindings(ctx context.Context, bindings engine.RoleBindings) error {
+	return a.write(func(writer engine.Writer) error {
+		return writer.AddRoleBindings(ctx, bindings)
+	})
+}
+
+// RemoveRoleBindings forwards the bindings to the wrapped authorizer and invalidates every cached result.
+func (a *Authorizer) RemoveRoleBindings(ctx context.Context, bindings engine.RoleBindings) error {
+	return a.write(func(writer engine.Writer) error {
+		return writer.RemoveRoleBindings(ctx, bindings)
+	})
+}
+
+func (a *Authorizer) write(fn func(writer engine.Writer) error) error {
+	writer, ok := a.authorizer.(engine.Writer)
+	if !ok {
+		return ErrNotWriter
+	}
+
+	defer a.Invalidate()
+
+	return fn(writer)
+}
+
+// Invalidate drops every cached result, results which are being computed while
+// Invalidate is called are not cached.
+func (a *Authorizer) Invalidate() {
+	a.generation.Add(1)
+	a.cache.purge()
+}
+
+func load[T any](a *Authorizer, key string, fn func() (T, error)) (T, error) {
+	gen := a.generation.Load()
+	key = generationKey(gen, key)
+
+	if v, ok := a.cache.get(key, a.now()); ok {
+		return v.(T), nil
+	}
+
+	v, err, _ := a.group.Do(key, func() (interface{}, error) {
+		r, err := fn()
+		if err != nil {
+			return nil, err
+		}
+		if a.generation.Load() == gen {
+			a.cache.add(key, r, a.now().Add(a.ttl))
+		}
+		return r, nil
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return v.(T), nil
+}
+
+func generationKey(gen uint64, key string) string {
+	return strconv.FormatUint(gen, 10) + keySeparator + key
+}
+
+func isAuthorizedKey(r engine.Request) string {
+	return makeKey("IsAuthorized", string(r.Subject), string(r.Action), string(r.Resource), string(r.Project))
+}
+
+func makeKey(parts ...string) string {
+	return strings.Join(parts, keySeparator)
+}
+
+func subjectsKey(subjects engine.Subjects) string {
+	parts := make([]string, 0, len(subjects))
+	for _, s := range subjects {
+		parts = append(parts, string(s))
+	}
+	return strings.Join(parts, listSeparator)
+}
+
+func projectsKey(projects engine.Projects) string {
+	parts := make([]string, 0, len(projects))
+	for _, p := range projects {
+		parts = append(parts, string(p))
+	}
+	return strings.Join(parts, listSeparator)
+}
+
+func pairsKey(pairs engine.Pairs) string {
+	parts := make([]string, 0, len(pairs))
+	for _, p := range pairs {
+		parts = append(parts, string(p.Resource)+fieldSeparator+string(p.Action))
+	}
+	return strings.Join(parts, listSeparator)
+}
@@ -0,0 +1,218 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+type countingEngine struct {
+	calls   atomic.Int32
+	allowed atomic.Bool
+	delay   time.Duration
+}
+
+func (e *countingEngine) Name() string {
+	return "counting"
+}
+
+func (e *countingEngine) ProjectsAuthorized(_ context.Context, _ engine.Subjects, _ engine.Action, _ engine.Resource, projects engine.Projects) (engine.Projects, error) {
+	e.calls.Add(1)
+	return projects, nil
+}
+
+func (e *countingEngine) FilterAuthorizedPairs(_ context.Context, _ engine.Subjects, pairs engine.Pairs) (engine.Pairs, error) {
+	e.calls.Add(1)
+	return pairs, nil
+}
+
+func (e *countingEngine) FilterAuthorizedProjects(_ context.Context, _ engine.Subjects) (engine.Projects, error) {
+	e.calls.Add(1)
+	return engine.MakeProjects("project1"), nil
+}
+
+func (e *countingEngine) IsAuthorized(_ context.Context, _ engine.Subject, _ engine.Action, _ engine.Resource, _ engine.Project) (bool, error) {
+	e.calls.Add(1)
+	time.Sleep(e.delay)
+	return e.allowed.Load(), nil
+}
+
+func (e *countingEngine) Decide(_ context.Context, _ engine.Subject, _ engine.Action, _ engine.Resource, _ engine.Project) (*engine.Decision, error) {
+	e.calls.Add(1)
+	if e.allowed.Load() {
+		return engine.MakeAllowDecision(e.Name()), nil
+	}
+	return engine.MakeDenyDecision(e.Name(), "denied"), nil
+}
+
+func (e *countingEngine) BatchIsAuthorized(_ context.Context, requests engine.Requests) ([]bool, error) {
+	e.calls.Add(1)
+	result := make([]bool, len(requests))
+	for i := range result {
+		result[i] = e.allowed.Load()
+	}
+	return result, nil
+}
+
+func (e *countingEngine) SetPolicies(_ context.Context, _ engine.PolicyMap, _ engine.RoleMap) error {
+	return nil
+}
+
+func (e *countingEngine) AddPolicies(_ context.Context, _ engine.Policies) error {
+	return nil
+}
+
+func (e *countingEngine) RemovePolicies(_ context.Context, _ engine.Policies) error {
+	return nil
+}
+
+func (e *countingEngine) AddRoleBindings(_ context.Context, _ engine.RoleBindings) error {
+	return nil
+}
+
+func (e *countingEngine) RemoveRoleBindings(_ context.Context, _ engine.RoleBindings) error {
+	return nil
+}
+
+func TestIsAuthorized(t *testing.T) {
+	e := &countingEngine{}
+	e.allowed.Store(true)
+	a := NewAuthorizer(e)
+
+	for i := 0; i < 3; i++ {
+		allowed, err := a.IsAuthorized(t.Context(), "bobo", "GET", "/api/users", "")
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+	}
+	assert.EqualValues(t, 1, e.calls.Load())
+
+	_, err := a.IsAuthorized(t.Context(), "bobo", "POST", "/api/users", "")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, e.calls.Load())
+}
+
+func TestTTL(t *testing.T) {
+	e := &countingEngine{}
+	now := time.Now()
+	a := NewAuthorizer(e, WithTTL(time.Second))
+	a.now = func() time.Time { return now }
+
+	_, _ = a.IsAuthorized(t.Context(), "bobo", "GET", "/api/users", "")
+	_, _ = a.IsAuthorized(t.Context(), "bobo", "GET", "/api/users", "")
+	assert.EqualValues(t, 1, e.calls.Load())
+
+	now = now.Add(2 * time.Second)
+	_, _ = a.IsAuthorized(t.Context(), "bobo", "GET", "/api/users", "")
+	assert.EqualValues(t, 2, e.calls.Load())
+}
+
+func TestMaxEntries(t *testing.T) {
+	e := &countingEngine{}
+	a := NewAuthorizer(e, WithMaxEntries(2))
+
+	for _, sub := range []engine.Subject{"a", "b", "c"} {
+		_, _ = a.IsAuthorized(t.Context(), sub, "GET", "/api/users", "")
+	}
+	assert.Equal(t, 2, a.cache.len())
+
+	_, _ = a.IsAuthorized(t.Context(), "c", "GET", "/api/users", "")
+	assert.EqualValues(t, 3, e.calls.Load())
+
+	_, _ = a.IsAuthorized(t.Context(), "a", "GET", "/api/users", "")
+	assert.EqualValues(t, 4, e.calls.Load())
+}
+
+func TestSetPoliciesInvalidates(t *testing.T) {
+	e := &countingEngine{}
+	a := NewAuthorizer(e)
+
+	allowed, _ := a.IsAuthorized(t.Context(), "bobo", "GET", "/api/users", "")
+	assert.False(t, allowed)
+
+	e.allowed.Store(true)
+	assert.Nil(t, a.SetPolicies(t.Context(), nil, nil))
+
+	allowed, _ = a.IsAuthorized(t.Context(), "bobo", "GET", "/api/users", "")
+	assert.True(t, allowed)
+	assert.EqualValues(t, 2, e.calls.Load())
+
+	e.allowed.Store(false)
+	assert.Nil(t, a.AddRoleBindings(t.Context(), engine.RoleBindings{{Subject: "bobo", Role: "admin"}}))
+
+	allowed, _ = a.IsAuthorized(t.Context(), "bobo", "GET", "/api/users", "")
+	assert.False(t, allowed)
+	assert.EqualValues(t, 3, e.calls.Load())
+}
+
+func TestSingleflight(t *testing.T) {
+	e := &countingEngine{delay: 50 * time.Millisecond}
+	a := NewAuthorizer(e)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = a.IsAuthorized(context.Background(), "bobo", "GET", "/api/users", "")
+		}()
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 1, e.calls.Load())
+}
+
+func TestFilterAuthorized(t *testing.T) {
+	e := &countingEngine{}
+	a := NewAuthorizer(e)
+
+	subjects := engine.MakeSubjects("bobo")
+	pairs := engine.MakePairs(engine.MakePair("/api/users", "GET"))
+
+	for i := 0; i < 2; i++ {
+		r, err := a.FilterAuthorizedPairs(t.Context(), subjects, pairs)
+		assert.Nil(t, err)
+		assert.EqualValues(t, pairs, r)
+
+		p, err := a.FilterAuthorizedProjects(t.Context(), subjects)
+		assert.Nil(t, err)
+		assert.EqualValues(t, engine.Projects{"project1"}, p)
+
+		p, err = a.ProjectsAuthorized(t.Context(), subjects, "GET", "/api/users", engine.MakeProjects("project2"))
+		assert.Nil(t, err)
+		assert.EqualValues(t, engine.Projects{"project2"}, p)
+	}
+	assert.EqualValues(t, 3, e.calls.Load())
+
+	// results handed out must not alias the cached ones
+	p, _ := a.FilterAuthorizedProjects(t.Context(), subjects)
+	p[0] = "changed"
+	p, _ = a.FilterAuthorizedProjects(t.Context(), subjects)
+	assert.EqualValues(t, engine.Projects{"project1"}, p)
+}
+
+func TestBatchIsAuthorized(t *testing.T) {
+	e := &countingEngine{}
+	e.allowed.Store(true)
+	a := NewAuthorizer(e)
+
+	_, _ = a.IsAuthorized(t.Context(), "bobo", "GET", "/api/users", "")
+
+	e.allowed.Store(false)
+	r, err := a.BatchIsAuthorized(t.Context(), engine.MakeRequests(
+		engine.MakeRequest("bobo", "GET", "/api/users", ""),
+		engine.MakeRequest("bobo", "POST", "/api/users", ""),
+	))
+	assert.Nil(t, err)
+	assert.Equal(t, []bool{true, false}, r)
+	assert.EqualValues(t, 2, e.calls.Load())
+
+	allowed, _ := a.IsAuthorized(t.Context(), "bobo", "POST", "/api/users", "")
+	assert.False(t, allowed)
+	assert.EqualValues(t, 2, e.calls.Load())
+}
@@ -0,0 +1,26 @@
+package cache
+
+import (
+	"time"
+)
+
+const (
+	DefaultTTL        = time.Minute
+	DefaultMaxEntries = 10000
+)
+
+type OptFunc func(*Authorizer)
+
+// WithTTL sets how long a cached result stays valid.
+func WithTTL(ttl time.Duration) OptFunc {
+	return func(a *Authorizer) {
+		a.ttl = ttl
+	}
+}
+
+// WithMaxEntries bounds the number of cached results, the least recently used results are evicted first.
+func WithMaxEntries(size int) OptFunc {
+	return func(a *Authorizer) {
+		a.maxEntries = size
+	}
+}
@@ -1,12 +1,26 @@
 package casbin
 
 import (
-	"errors"
+	"maps"
+	"slices"
+	"sync"
+
 	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+var (
+	_ persist.BatchAdapter     = (*Adapter)(nil)
+	_ persist.UpdatableAdapter = (*Adapter)(nil)
+	_ persist.FilteredAdapter  = (*Adapter)(nil)
 )
 
+// Adapter keeps the rules in memory, under the "policies" key of its policy map. It supports the
+// AutoSave of the enforcer management APIs, so the rules stay in sync with the enforcer.
 type Adapter struct {
+	mu       sync.RWMutex
 	policies map[string]interface{}
+	filtered bool
 }
 
 func newAdapter() *Adapter {
@@ -16,34 +30,174 @@ func newAdapter() *Adapter {
 }
 
 func (sa *Adapter) LoadPolicy(model model.Model) error {
-	policiesInterface, ok := sa.policies["policies"]
-	if ok {
-		policies := policiesInterface.([]PolicyRule)
-		for _, line := range policies {
-			if err := line.LoadPolicyLine(model); err != nil {
-				return err
-			}
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	sa.filtered = false
+	for _, line := range sa.rules() {
+		if err := line.LoadPolicyLine(model); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-func (sa *Adapter) SavePolicy(_ model.Model) error {
-	return errors.New("not implemented")
+// LoadFilteredPolicy loads the rules selected by a Filter.
+func (sa *Adapter) LoadFilteredPolicy(model model.Model, filter interface{}) error {
+	f, err := asFilter(filter)
+	if err != nil {
+		return err
+	}
+
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	sa.filtered = f != nil
+	if ok, err := f.loadSnapshot(model); ok {
+		return err
+	}
+	return loadFilteredRules(model, sa.rules(), f)
 }
 
-func (sa *Adapter) AddPolicy(_ string, _ string, _ []string) error {
-	return errors.New("not implemented")
+func (sa *Adapter) IsFiltered() bool {
+	sa.mu.RLock()
+	defer sa.mu.RUnlock()
+
+	return sa.filtered
 }
 
-func (sa *Adapter) RemovePolicy(_ string, _ string, _ []string) error {
-	return errors.New("not implemented")
+// SavePolicy replaces the rules with the ones of the model.
+func (sa *Adapter) SavePolicy(model model.Model) error {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	sa.policies["policies"] = modelRules(model)
+	return nil
 }
 
-func (sa *Adapter) RemoveFilteredPolicy(_ string, _ string, _ int, _ ...string) error {
-	return errors.New("not implemented")
+func (sa *Adapter) AddPolicy(_ string, ptype string, rule []string) error {
+	return sa.AddPolicies("", ptype, [][]string{rule})
 }
 
+func (sa *Adapter) RemovePolicy(_ string, ptype string, rule []string) error {
+	return sa.RemovePolicies("", ptype, [][]string{rule})
+}
+
+func (sa *Adapter) AddPolicies(_ string, ptype string, rules [][]string) error {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	sa.policies["policies"] = append(slices.Clone(sa.rules()), policyRules(ptype, rules)...)
+	return nil
+}
+
+func (sa *Adapter) RemovePolicies(_ string, ptype string, rules [][]string) error {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	removed := policyRules(ptype, rules)
+	sa.policies["policies"] = slices.DeleteFunc(slices.Clone(sa.rules()), func(line PolicyRule) bool {
+		return slices.Contains(removed, line)
+	})
+	return nil
+}
+
+func (sa *Adapter) RemoveFilteredPolicy(_ string, ptype string, fieldIndex int, fieldValues ...string) error {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	sa.removeFiltered(ptype, fieldIndex, fieldValues...)
+	return nil
+}
+
+func (sa *Adapter) UpdatePolicy(_ string, ptype string, oldRule, newRule []string) error {
+	return sa.UpdatePolicies("", ptype, [][]string{oldRule}, [][]string{newRule})
+}
+
+func (sa *Adapter) UpdatePolicies(_ string, ptype string, oldRules, newRules [][]string) error {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	current := slices.Clone(sa.rules())
+	for i, oldRule := range oldRules {
+		if i >= len(newRules) {
+			break
+		}
+		if index := slices.Index(current, newPolicyRule(ptype, oldRule)); index >= 0 {
+			current[index] = newPolicyRule(ptype, newRules[i])
+		}
+	}
+	sa.policies["policies"] = current
+	return nil
+}
+
+func (sa *Adapter) UpdateFilteredPolicies(_ string, ptype string, newRules [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	oldRules := sa.removeFiltered(ptype, fieldIndex, fieldValues...)
+	sa.policies["policies"] = append(sa.rules(), policyRules(ptype, newRules)...)
+
+	return oldRules, nil
+}
+
+// SetPolicies replaces the policy map with a copy of policies, the adapter writes the rules into it.
 func (sa *Adapter) SetPolicies(policies map[string]interface{}) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	sa.policies = make(map[string]interface{}, len(policies))
+	maps.Copy(sa.policies, policies)
+}
+
+// allRules returns a copy of the rules.
+func (sa *Adapter) allRules() []PolicyRule {
+	sa.mu.RLock()
+	defer sa.mu.RUnlock()
+
+	return slices.Clone(sa.rules())
+}
+
+// setRules replaces the rules, the other entries of the policy map are kept.
+func (sa *Adapter) setRules(rules []PolicyRule) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	policies := make(map[string]interface{}, len(sa.policies))
+	for k, v := range sa.policies {
+		policies[k] = v
+	}
+	policies["policies"] = rules
 	sa.policies = policies
 }
+
+// removeFiltered removes the rules matching the filter, and returns their values.
+func (sa *Adapter) removeFiltered(ptype string, fieldIndex int, fieldValues ...string) [][]string {
+	var removed [][]string
+	sa.policies["policies"] = slices.DeleteFunc(slices.Clone(sa.rules()), func(line PolicyRule) bool {
+		if line.PType != ptype || !line.matches(fieldIndex, fieldValues...) {
+			return false
+		}
+		removed = append(removed, line.values())
+		return true
+	})
+	return removed
+}
+
+func (sa *Adapter) rules() []PolicyRule {
+	rules, _ := sa.policies["policies"].([]PolicyRule)
+	return rules
+}
+
+// modelRules returns the policy ("p") and grouping ("g") rules of the model.
+func modelRules(m model.Model) []PolicyRule {
+	var rules []PolicyRule
+	for _, sec := range []string{"p", "g"} {
+		for ptype, assertion := range m[sec] {
+			for _, rule := range assertion.Policy {
+				rules = append(rules, newPolicyRule(ptype, rule))
+			}
+		}
+	}
+	return rules
+}
@@ -0,0 +1,101 @@
+package casbin
+
+import (
+	"testing"
+
+	stdCasbin "github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tx7do/kratos-authz/engine/casbin/assets"
+)
+
+// testAdapter runs the enforcer management APIs on an adapter, and checks that a new enforcer
+// loads the same rules from it.
+func testAdapter(t *testing.T, adapter persist.Adapter) {
+	m, err := model.NewModelFromString(assets.DefaultRestfullWithRoleModel)
+	require.NoError(t, err)
+
+	enforcer, err := stdCasbin.NewSyncedEnforcer(m, adapter)
+	require.NoError(t, err)
+
+	reload := func() *stdCasbin.SyncedEnforcer {
+		m, err := model.NewModelFromString(assets.DefaultRestfullWithRoleModel)
+		require.NoError(t, err)
+		e, err := stdCasbin.NewSyncedEnforcer(m, adapter)
+		require.NoError(t, err)
+		return e
+	}
+
+	_, err = enforcer.AddPolicy("bobo", "/api/users", "GET", "project1")
+	require.NoError(t, err)
+	_, err = enforcer.AddPolicies([][]string{
+		{"bobo", "/api/users", "POST", "project1"},
+		{"alice", "/api/users", "GET", "project2"},
+	})
+	require.NoError(t, err)
+	_, err = enforcer.AddGroupingPolicy("admin", "bobo", "project1")
+	require.NoError(t, err)
+
+	e := reload()
+	policies, _ := e.GetPolicy()
+	assert.ElementsMatch(t, [][]string{
+		{"bobo", "/api/users", "GET", "project1"},
+		{"bobo", "/api/users", "POST", "project1"},
+		{"alice", "/api/users", "GET", "project2"},
+	}, policies)
+	groupings, _ := e.GetGroupingPolicy()
+	assert.Equal(t, [][]string{{"admin", "bobo", "project1"}}, groupings)
+
+	_, err = enforcer.UpdatePolicy([]string{"alice", "/api/users", "GET", "project2"}, []string{"alice", "/api/orders", "GET", "project2"})
+	require.NoError(t, err)
+	_, err = enforcer.RemovePolicy("bobo", "/api/users", "POST", "project1")
+	require.NoError(t, err)
+
+	policies, _ = reload().GetPolicy()
+	assert.ElementsMatch(t, [][]string{
+		{"bobo", "/api/users", "GET", "project1"},
+		{"alice", "/api/orders", "GET", "project2"},
+	}, policies)
+
+	_, err = enforcer.RemoveFilteredPolicy(0, "bobo")
+	require.NoError(t, err)
+	_, err = enforcer.UpdateFilteredPolicies([][]string{{"alice", "/api/orders", "*", "project2"}}, 0, "alice", "", "GET")
+	require.NoError(t, err)
+
+	policies, _ = reload().GetPolicy()
+	assert.Equal(t, [][]string{{"alice", "/api/orders", "*", "project2"}}, policies)
+
+	_, err = enforcer.RemoveGroupingPolicy("admin", "bobo", "project1")
+	require.NoError(t, err)
+	_, err = enforcer.AddPolicy("carol", "/api/users", "GET", "project3")
+	require.NoError(t, err)
+	require.NoError(t, enforcer.SavePolicy())
+
+	e = reload()
+	policies, _ = e.GetPolicy()
+	assert.ElementsMatch(t, [][]string{
+		{"alice", "/api/orders", "*", "project2"},
+		{"carol", "/api/users", "GET", "project3"},
+	}, policies)
+	groupings, _ = e.GetGroupingPolicy()
+	assert.Empty(t, groupings)
+}
+
+func TestAdapter(t *testing.T) {
+	testAdapter(t, newAdapter())
+}
+
+func TestAdapter_SetPolicies(t *testing.T) {
+	policies := map[string]interface{}{}
+
+	adapter := newAdapter()
+	adapter.SetPolicies(policies)
+	require.NoError(t, adapter.AddPolicy("", "p", []string{"bobo", "/api/users", "GET", "project1"}))
+
+	// the caller's map is left unchanged
+	assert.Empty(t, policies)
+	assert.Len(t, adapter.allRules(), 1)
+}
@@ -2,11 +2,15 @@ package casbin
 
 import (
 	"context"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-kratos/kratos/v2/log"
 
 	stdCasbin "github.com/casbin/casbin/v2"
 	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
 
 	"github.com/tx7do/kratos-authz/engine"
 	"github.com/tx7do/kratos-authz/engine/casbin/assets"
@@ -30,14 +34,29 @@ func init() {
 var _ engine.Engine = (*State)(nil)
 
 type State struct {
-	model    model.Model
-	policy   *Adapter
-	enforcer *stdCasbin.SyncedEnforcer
+	model  model.Model
+	policy persist.Adapter
+	// definition is the model without rules, copied to read the rules before replacing the ones of the enforcer
+	definition model.Model
+	enforcer   *stdCasbin.SyncedEnforcer
 
 	projects                  engine.Projects
+	roles                     engine.Roles
 	wildcardItem              string
 	authorizedProjectsMatcher string
 
+	// policies given with WithPolices to an adapter other than the in-memory one, saved once the enforcer is created
+	initialPolicies engine.PolicyMap
+
+	watchInterval time.Duration
+	poller        *filePoller
+
+	watcher *Watcher
+
+	// filter selects the projects the rules are loaded for, nil when every rule is loaded
+	filter   *Filter
+	filterMu sync.Mutex
+
 	log *log.Helper
 }
 
@@ -72,12 +91,48 @@ func (s *State) init(opts ...OptFunc) error {
 		}
 	}
 
-	s.enforcer, err = stdCasbin.NewSyncedEnforcer(s.model, s.policy)
+	if s.filter != nil {
+		s.filter.Wildcard = s.wildcardItem
+	}
+
+	s.definition = s.model.Copy()
+	s.definition.ClearPolicy()
+
+	if s.filter == nil {
+		s.enforcer, err = stdCasbin.NewSyncedEnforcer(s.model, s.policy)
+	} else {
+		// the enforcer would load every rule when created with the adapter
+		if s.enforcer, err = stdCasbin.NewSyncedEnforcer(s.model); err == nil {
+			s.enforcer.SetAdapter(s.policy)
+			err = s.enforcer.LoadFilteredPolicy(s.filter)
+		}
+	}
 	if err != nil {
 		s.log.Errorf("failed to create casbin enforcer: %v", err)
 		return err
 	}
 
+	if s.initialPolicies != nil {
+		if err = s.loadPolicies(s.initialPolicies); err != nil {
+			return err
+		}
+	}
+
+	if s.watcher != nil {
+		if err = s.enforcer.SetWatcher(s.watcher); err != nil {
+			s.log.Errorf("failed to set policy watcher: %v", err)
+			return err
+		}
+		s.watcher.setHandler(s.applyWatcherMessage)
+	}
+
+	if s.watchInterval > 0 {
+		if err = s.startWatch(); err != nil {
+			s.log.Errorf("failed to watch policy file: %v", err)
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -124,21 +179,48 @@ func (s *State) FilterAuthorizedPairs(_ context.Context, subjects engine.Subject
 	return result, nil
 }
 
+// FilterAuthorizedProjects returns the projects the subjects are authorized for. The projects are the
+// domains of the loaded rules, restricted to the ones given with WithProjects when set.
 func (s *State) FilterAuthorizedProjects(_ context.Context, subjects engine.Subjects) (engine.Projects, error) {
-	result := make(engine.Projects, 0, len(s.projects))
+	known := s.knownProjects()
+
+	candidates := make(map[engine.Project]bool, len(known))
+	for _, subject := range subjects {
+		domains, all, err := s.subjectDomains(string(subject))
+		if err != nil {
+			s.log.Errorf("failed to get the domains of subject %s: %v", subject, err)
+			return nil, err
+		}
+		if all {
+			candidates = nil
+			break
+		}
+		for _, domain := range domains {
+			candidates[engine.Project(domain)] = true
+		}
+	}
+
+	result := make(engine.Projects, 0, len(known))
 
 	resource := engine.Resource(s.wildcardItem)
 	action := engine.Action(s.wildcardItem)
 
 	var err error
 	var allowed bool
-	for _, project := range s.projects {
+	for _, project := range known {
+		if candidates != nil && !candidates[project] {
+			continue
+		}
+		if !s.isLoaded(project) {
+			continue
+		}
 		for _, subject := range subjects {
 			if allowed, err = s.enforcer.EnforceWithMatcher(s.authorizedProjectsMatcher, string(subject), string(resource), string(action), string(project)); err != nil {
 				s.log.Errorf("failed to enforce policy with matcher: %v", err)
 				return nil, err
 			} else if allowed {
 				result = append(result, project)
+				break
 			}
 		}
 	}
@@ -146,30 +228,77 @@ func (s *State) FilterAuthorizedProjects(_ context.Context, subjects engine.Subj
 	return result, nil
 }
 
-func (s *State) IsAuthorized(_ context.Context, subject engine.Subject, action engine.Action, resource engine.Resource, project engine.Project) (bool, error) {
+func (s *State) IsAuthorized(ctx context.Context, subject engine.Subject, action engine.Action, resource engine.Resource, project engine.Project) (bool, error) {
+	decision, err := s.Decide(ctx, subject, action, resource, project)
+	if err != nil {
+		return false, err
+	}
+	return decision.Allowed(), nil
+}
+
+func (s *State) Decide(_ context.Context, subject engine.Subject, action engine.Action, resource engine.Resource, project engine.Project) (*engine.Decision, error) {
+	start := time.Now()
+
 	if len(project) == 0 {
 		project = engine.Project(s.wildcardItem)
 	}
 
-	var err error
-	var allowed bool
-	if allowed, err = s.enforcer.Enforce(string(subject), string(resource), string(action), string(project)); err != nil {
+	allowed, explain, err := s.enforcer.EnforceEx(string(subject), string(resource), string(action), string(project))
+	if err != nil {
 		s.log.Errorf("failed to enforce policy: %v", err)
-		return false, err
-	} else if allowed {
-		return true, nil
+		return nil, err
 	}
-	return false, nil
+
+	var decision *engine.Decision
+	switch {
+	case allowed:
+		decision = engine.MakeAllowDecision(s.Name(), explainToRule(explain)...)
+	case len(explain) > 0:
+		decision = engine.MakeDenyDecision(s.Name(), "denied by policy rule", explainToRule(explain)...)
+	default:
+		decision = engine.MakeDenyDecision(s.Name(), "no matching policy rule")
+	}
+	decision.Duration = time.Since(start)
+
+	return decision, nil
 }
 
-func (s *State) SetPolicies(_ context.Context, policyMap engine.PolicyMap, _ engine.RoleMap) error {
-	s.policy.SetPolicies(policyMap)
+func (s *State) BatchIsAuthorized(_ context.Context, requests engine.Requests) ([]bool, error) {
+	if len(requests) == 0 {
+		return []bool{}, nil
+	}
 
-	if err := s.enforcer.LoadPolicy(); err != nil {
-		s.log.Errorf("failed to load policy: %v", err)
+	rvals := make([][]interface{}, 0, len(requests))
+	for _, r := range requests {
+		project := r.Project
+		if len(project) == 0 {
+			project = engine.Project(s.wildcardItem)
+		}
+		rvals = append(rvals, []interface{}{string(r.Subject), string(r.Resource), string(r.Action), string(project)})
+	}
+
+	result, err := s.enforcer.BatchEnforce(rvals)
+	if err != nil {
+		s.log.Errorf("failed to batch enforce policy: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *State) SetPolicies(_ context.Context, policyMap engine.PolicyMap, roleMap engine.RoleMap) error {
+	policyMap, err := s.translatePolicies(policyMap, roleMap)
+	if err != nil {
+		s.log.Errorf("failed to translate policies: %v", err)
+		return err
+	}
+
+	if err = s.loadPolicies(policyMap); err != nil {
 		return err
 	}
 
+	s.notifyPolicies()
+
 	//fmt.Println(err, s.enforcer.GetAllSubjects(), s.enforcer.GetAllRoles())
 
 	projects, ok := policyMap["projects"]
@@ -182,3 +311,142 @@ func (s *State) SetPolicies(_ context.Context, policyMap engine.PolicyMap, _ eng
 
 	return nil
 }
+
+// AddPolicies adds the rules of the policies to the enforcer, without reloading the other rules.
+func (s *State) AddPolicies(_ context.Context, policies engine.Policies) error {
+	rules, err := rulesFromPolicies(policies, s.roles, nil, s.wildcardItem)
+	if err != nil {
+		s.log.Errorf("failed to translate policies: %v", err)
+		return err
+	}
+
+	p, _ := splitRules(rules)
+	if len(p) > 0 {
+		if _, err = s.enforcer.AddPoliciesEx(p); err != nil {
+			s.log.Errorf("failed to add policies: %v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RemovePolicies removes the rules of the policies from the enforcer, without reloading the other rules.
+func (s *State) RemovePolicies(_ context.Context, policies engine.Policies) error {
+	rules, err := rulesFromPolicies(policies, s.roles, nil, s.wildcardItem)
+	if err != nil {
+		s.log.Errorf("failed to translate policies: %v", err)
+		return err
+	}
+
+	p, _ := splitRules(rules)
+	if len(p) > 0 {
+		if _, err = s.enforcer.RemovePolicies(p); err != nil {
+			s.log.Errorf("failed to remove policies: %v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AddRoleBindings adds the grouping rules of the bindings to the enforcer.
+func (s *State) AddRoleBindings(_ context.Context, bindings engine.RoleBindings) error {
+	rules, err := rulesFromPolicies(nil, nil, bindings, s.wildcardItem)
+	if err != nil {
+		return err
+	}
+
+	_, g := splitRules(rules)
+	if len(g) > 0 {
+		if _, err = s.enforcer.AddGroupingPoliciesEx(g); err != nil {
+			s.log.Errorf("failed to add role bindings: %v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RemoveRoleBindings removes the grouping rules of the bindings from the enforcer.
+func (s *State) RemoveRoleBindings(_ context.Context, bindings engine.RoleBindings) error {
+	rules, err := rulesFromPolicies(nil, nil, bindings, s.wildcardItem)
+	if err != nil {
+		return err
+	}
+
+	_, g := splitRules(rules)
+	if len(g) > 0 {
+		if _, err = s.enforcer.RemoveGroupingPolicies(g); err != nil {
+			s.log.Errorf("failed to remove role bindings: %v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadPolicies replaces the rules of the adapter with the ones of the policy map, and reloads the enforcer.
+// The adapters other than the in-memory one are written through SavePolicy.
+func (s *State) loadPolicies(policyMap engine.PolicyMap) error {
+	if adapter, ok := s.policy.(*Adapter); ok {
+		adapter.SetPolicies(policyMap)
+	} else {
+		rules := newAdapter()
+		rules.SetPolicies(policyMap)
+
+		m := s.enforcer.GetModel().Copy()
+		m.ClearPolicy()
+		if err := rules.LoadPolicy(m); err != nil {
+			s.log.Errorf("failed to load policy: %v", err)
+			return err
+		}
+		if err := s.policy.SavePolicy(m); err != nil {
+			s.log.Errorf("failed to save policy: %v", err)
+			return err
+		}
+	}
+
+	if err := s.reloadPolicy(); err != nil {
+		s.log.Errorf("failed to load policy: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// translatePolicies converts the engine-neutral policy model (if any) into casbin rules,
+// appended to the rules given under the "policies" key.
+func (s *State) translatePolicies(policyMap engine.PolicyMap, roleMap engine.RoleMap) (engine.PolicyMap, error) {
+	policies, hasPolicies := policyMap.Policies()
+	roles, _ := roleMap.Roles()
+	bindings, hasBindings := roleMap.RoleBindings()
+	s.roles = roles
+	if !hasPolicies && !hasBindings {
+		return policyMap, nil
+	}
+
+	rules, err := rulesFromPolicies(policies, roles, bindings, s.wildcardItem)
+	if err != nil {
+		return nil, err
+	}
+
+	translated := engine.PolicyMap{}
+	for k, v := range policyMap {
+		translated[k] = v
+	}
+	if raw, ok := policyMap["policies"].([]PolicyRule); ok {
+		rules = append(append([]PolicyRule{}, raw...), rules...)
+	}
+	translated["policies"] = rules
+
+	return translated, nil
+}
+
+// explainToRule converts the rule returned by EnforceEx into the matched policy list of a Decision.
+func explainToRule(explain []string) []string {
+	if len(explain) == 0 {
+		return nil
+	}
+	return []string{strings.Join(explain, ", ")}
+}
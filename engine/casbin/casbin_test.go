@@ -152,6 +152,63 @@ func TestFilterAuthorizedProjects(t *testing.T) {
 	}
 }
 
+func TestFilterAuthorizedProjectsFromDomains(t *testing.T) {
+	policies := map[string]interface{}{
+		"policies": []PolicyRule{
+			{PType: "p", V0: "bobo", V1: "/api/*", V2: "GET", V3: "project1"},
+			{PType: "p", V0: "reader", V1: "/api/*", V2: "GET", V3: "project3"},
+			{PType: "p", V0: "reader", V1: "/api/*", V2: "GET", V3: "project4"},
+			{PType: "p", V0: "admin_role", V1: "/api/*", V2: "(GET)|(POST)", V3: "*"},
+			{PType: "g", V0: "alice", V1: "reader", V2: "project3"},
+			{PType: "g", V0: "viewer", V1: "reader", V2: "project4"},
+			{PType: "g", V0: "carol", V1: "viewer", V2: "project4"},
+			{PType: "g", V0: "admin", V1: "admin_role", V2: "*"},
+		},
+	}
+
+	s, err := NewEngine(t.Context(), WithPolices(policies))
+	assert.Nil(t, err)
+
+	tests := []struct {
+		subjects engine.Subjects
+		equal    engine.Projects
+	}{
+		{subjects: engine.MakeSubjects("bobo"), equal: engine.Projects{"project1"}},
+		{subjects: engine.MakeSubjects("alice"), equal: engine.Projects{"project3"}},
+		{subjects: engine.MakeSubjects("carol"), equal: engine.Projects{"project4"}},
+		{subjects: engine.MakeSubjects("bobo", "alice"), equal: engine.Projects{"project1", "project3"}},
+		{subjects: engine.MakeSubjects("admin"), equal: engine.Projects{"project1", "project3", "project4"}},
+		{subjects: engine.MakeSubjects("nobody"), equal: engine.Projects{}},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.subjects[0]), func(t *testing.T) {
+			r, err := s.FilterAuthorizedProjects(t.Context(), test.subjects)
+			assert.Nil(t, err)
+			assert.EqualValues(t, test.equal, r)
+		})
+	}
+
+	s, err = NewEngine(t.Context(), WithPolices(policies), WithProjects(engine.MakeProjects("project3", "project5")))
+	assert.Nil(t, err)
+
+	r, err := s.FilterAuthorizedProjects(t.Context(), engine.MakeSubjects("bobo", "alice"))
+	assert.Nil(t, err)
+	assert.EqualValues(t, engine.Projects{"project3"}, r)
+
+	r, err = s.FilterAuthorizedProjects(t.Context(), engine.MakeSubjects("admin"))
+	assert.Nil(t, err)
+	assert.EqualValues(t, engine.Projects{"project3", "project5"}, r)
+
+	// without a wildcard rule, only the projects of the rules are known
+	s, err = NewEngine(t.Context(),
+		WithPolices(map[string]interface{}{"policies": policies["policies"].([]PolicyRule)[:3]}),
+		WithProjects(engine.MakeProjects("project4", "project5", "project1")),
+	)
+	assert.Nil(t, err)
+	assert.EqualValues(t, engine.Projects{"project4", "project1"}, s.knownProjects())
+}
+
 func TestProjectsAuthorized(t *testing.T) {
 	s, err := NewEngine(t.Context())
 	assert.Nil(t, err)
@@ -301,3 +358,228 @@ func TestProjectsAuthorized(t *testing.T) {
 		})
 	}
 }
+
+func TestDecide(t *testing.T) {
+	s, err := NewEngine(t.Context())
+	assert.Nil(t, err)
+	assert.NotNil(t, s)
+
+	policies := map[string]interface{}{
+		"policies": []PolicyRule{
+			{PType: "p", V0: "bobo", V1: "/api/*", V2: "(GET)|(POST)", V3: "project1"},
+			{PType: "p", V0: "admin_role", V1: "/api/*", V2: "(GET)|(POST)", V3: "*"},
+			{PType: "g", V0: "admin", V1: "admin_role", V2: "*"},
+		},
+	}
+
+	err = s.SetPolicies(t.Context(), policies, nil)
+	assert.Nil(t, err)
+
+	tests := []struct {
+		subject engine.Subject
+		action  engine.Action
+		path    engine.Resource
+		project engine.Project
+		effect  engine.Effect
+		matched []string
+	}{
+		{
+			subject: "bobo",
+			action:  "GET",
+			path:    "/api/users",
+			project: "project1",
+			effect:  engine.EffectAllow,
+			matched: []string{"bobo, /api/*, (GET)|(POST), project1"},
+		},
+		{
+			subject: "admin",
+			action:  "POST",
+			path:    "/api/users",
+			effect:  engine.EffectAllow,
+			matched: []string{"admin_role, /api/*, (GET)|(POST), *"},
+		},
+		{
+			subject: "bobo",
+			action:  "GET",
+			path:    "/api/users",
+			project: "project2",
+			effect:  engine.EffectDeny,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.subject), func(t *testing.T) {
+			d, err := s.Decide(t.Context(), test.subject, test.action, test.path, test.project)
+			assert.Nil(t, err)
+			assert.Equal(t, test.effect, d.Effect)
+			assert.Equal(t, string(engine.Casbin), d.Engine)
+			assert.EqualValues(t, test.matched, d.MatchedPolicies)
+			if test.effect == engine.EffectDeny {
+				assert.NotEmpty(t, d.Reason)
+			}
+
+			allowed, err := s.IsAuthorized(t.Context(), test.subject, test.action, test.path, test.project)
+			assert.Nil(t, err)
+			assert.Equal(t, d.Allowed(), allowed)
+		})
+	}
+}
+
+func TestBatchIsAuthorized(t *testing.T) {
+	s, err := NewEngine(t.Context())
+	assert.Nil(t, err)
+	assert.NotNil(t, s)
+
+	policies := map[string]interface{}{
+		"policies": []PolicyRule{
+			{PType: "p", V0: "bobo", V1: "/api/*", V2: "(GET)|(POST)", V3: "project1"},
+			{PType: "p", V0: "admin_role", V1: "/api/*", V2: "(GET)|(POST)", V3: "*"},
+			{PType: "g", V0: "admin", V1: "admin_role", V2: "*"},
+		},
+	}
+
+	err = s.SetPolicies(t.Context(), policies, nil)
+	assert.Nil(t, err)
+
+	requests := engine.MakeRequests(
+		engine.MakeRequest("bobo", "GET", "/api/users", "project1"),
+		engine.MakeRequest("bobo", "GET", "/api/users", "project2"),
+		engine.MakeRequest("admin", "POST", "/api/users", ""),
+		engine.MakeRequest("admin", "DELETE", "/api/users", ""),
+	)
+
+	r, err := s.BatchIsAuthorized(t.Context(), requests)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []bool{true, false, true, false}, r)
+
+	for i, req := range requests {
+		allowed, err := s.IsAuthorized(t.Context(), req.Subject, req.Action, req.Resource, req.Project)
+		assert.Nil(t, err)
+		assert.Equal(t, allowed, r[i])
+	}
+}
+
+func TestTypedPolicies(t *testing.T) {
+	s, err := NewEngine(t.Context())
+	assert.Nil(t, err)
+	assert.NotNil(t, s)
+
+	policies := engine.MakePolicyMap(
+		engine.Policy{
+			ID:      "pol-bobo",
+			Members: engine.MakeSubjects("bobo"),
+			Statements: engine.Statements{
+				{
+					Effect:    engine.EffectAllow,
+					Resources: engine.MakeResources("/api/*"),
+					Actions:   engine.MakeActions("GET"),
+					Projects:  engine.MakeProjects("project1", "project2"),
+				},
+			},
+		},
+		engine.Policy{
+			ID:      "pol-admin",
+			Members: engine.MakeSubjects("admin_role"),
+			Statements: engine.Statements{
+				{
+					Effect:    engine.EffectAllow,
+					Resources: engine.MakeResources("/api/*"),
+					Role:      "writer",
+				},
+			},
+		},
+	)
+	roles := engine.MakeRoleMap(
+		engine.Roles{{ID: "writer", Actions: engine.MakeActions("POST", "DELETE")}},
+		engine.RoleBindings{{Subject: "admin", Role: "admin_role"}},
+	)
+
+	err = s.SetPolicies(t.Context(), policies, roles)
+	assert.Nil(t, err)
+
+	tests := []struct {
+		subject engine.Subject
+		action  engine.Action
+		path    engine.Resource
+		project engine.Project
+		allowed bool
+	}{
+		{subject: "bobo", action: "GET", path: "/api/users", project: "project1", allowed: true},
+		{subject: "bobo", action: "GET", path: "/api/users", project: "project3", allowed: false},
+		{subject: "bobo", action: "POST", path: "/api/users", project: "project1", allowed: false},
+		{subject: "admin", action: "DELETE", path: "/api/users", project: "project3", allowed: true},
+		{subject: "admin", action: "GET", path: "/api/users", project: "", allowed: false},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.subject), func(t *testing.T) {
+			allowed, err := s.IsAuthorized(t.Context(), test.subject, test.action, test.path, test.project)
+			assert.Nil(t, err)
+			assert.Equal(t, test.allowed, allowed)
+		})
+	}
+
+	r, err := s.FilterAuthorizedProjects(t.Context(), engine.MakeSubjects("bobo"))
+	assert.Nil(t, err)
+	assert.EqualValues(t, engine.Projects{"project1", "project2"}, r)
+
+	err = s.SetPolicies(t.Context(), engine.MakePolicyMap(engine.Policy{
+		ID:         "pol-deny",
+		Members:    engine.MakeSubjects("bobo"),
+		Statements: engine.Statements{{Effect: engine.EffectDeny, Resources: engine.MakeResources("/api/*")}},
+	}), nil)
+	assert.ErrorIs(t, err, ErrDenyNotSupported)
+}
+
+func TestIncrementalPolicies(t *testing.T) {
+	s, err := NewEngine(t.Context())
+	assert.Nil(t, err)
+
+	err = s.SetPolicies(t.Context(),
+		engine.MakePolicyMap(engine.Policy{
+			ID:      "pol-admin",
+			Members: engine.MakeSubjects("admin_role"),
+			Statements: engine.Statements{
+				{Effect: engine.EffectAllow, Resources: engine.MakeResources("/api/*"), Role: "writer"},
+			},
+		}),
+		engine.MakeRoleMap(engine.Roles{{ID: "writer", Actions: engine.MakeActions("POST")}}, nil),
+	)
+	assert.Nil(t, err)
+
+	policy := engine.Policy{
+		ID:      "pol-bobo",
+		Members: engine.MakeSubjects("bobo"),
+		Statements: engine.Statements{
+			{Effect: engine.EffectAllow, Resources: engine.MakeResources("/api/users"), Actions: engine.MakeActions("GET"), Projects: engine.MakeProjects("project1")},
+		},
+	}
+
+	allowed, err := s.IsAuthorized(t.Context(), "bobo", "GET", "/api/users", "project1")
+	assert.Nil(t, err)
+	assert.False(t, allowed)
+
+	assert.Nil(t, s.AddPolicies(t.Context(), engine.Policies{policy}))
+	allowed, err = s.IsAuthorized(t.Context(), "bobo", "GET", "/api/users", "project1")
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+	projects, err := s.FilterAuthorizedProjects(t.Context(), engine.MakeSubjects("bobo"))
+	assert.Nil(t, err)
+	assert.EqualValues(t, engine.Projects{"project1"}, projects)
+
+	assert.Nil(t, s.RemovePolicies(t.Context(), engine.Policies{policy}))
+	allowed, err = s.IsAuthorized(t.Context(), "bobo", "GET", "/api/users", "project1")
+	assert.Nil(t, err)
+	assert.False(t, allowed)
+
+	bindings := engine.RoleBindings{{Subject: "alice", Role: "admin_role"}}
+	assert.Nil(t, s.AddRoleBindings(t.Context(), bindings))
+	allowed, err = s.IsAuthorized(t.Context(), "alice", "POST", "/api/users", "")
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+
+	assert.Nil(t, s.RemoveRoleBindings(t.Context(), bindings))
+	allowed, err = s.IsAuthorized(t.Context(), "alice", "POST", "/api/users", "")
+	assert.Nil(t, err)
+	assert.False(t, allowed)
+}
@@ -0,0 +1,277 @@
+package casbin
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrUnknownFileFormat = errors.New("casbin: unknown policy file format")
+
+// FileFormat is the encoding of a policy file.
+type FileFormat string
+
+const (
+	// FileFormatCSV is the standard Casbin format, a rule per line such as "p, alice, data1, read".
+	FileFormatCSV FileFormat = "csv"
+	// FileFormatJSON is a JSON list of PolicyRule.
+	FileFormatJSON FileFormat = "json"
+	// FileFormatYAML is a YAML list of PolicyRule.
+	FileFormatYAML FileFormat = "yaml"
+)
+
+var _ persist.FilteredAdapter = (*FileAdapter)(nil)
+
+type FileAdapterOption func(*FileAdapter)
+
+// WithFileFormat sets the format of the file, which is otherwise given by its extension.
+func WithFileFormat(format FileFormat) FileAdapterOption {
+	return func(a *FileAdapter) {
+		a.format = format
+	}
+}
+
+// FileAdapter loads the rules from a CSV, JSON or YAML file, and saves them to it with SavePolicy.
+// The enforcer changes are not saved to the file one by one, they are kept in memory until SavePolicy.
+type FileAdapter struct {
+	path   string
+	format FileFormat
+
+	mu       sync.Mutex
+	modTime  time.Time
+	size     int64
+	filtered bool
+}
+
+// NewFileAdapter creates the adapter of the file at path, the format is given by the .csv, .json,
+// .yaml or .yml extension unless set with WithFileFormat.
+func NewFileAdapter(path string, opts ...FileAdapterOption) (*FileAdapter, error) {
+	a := &FileAdapter{path: path}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		a.format = FileFormatCSV
+	case ".json":
+		a.format = FileFormatJSON
+	case ".yaml", ".yml":
+		a.format = FileFormatYAML
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	switch a.format {
+	case FileFormatCSV, FileFormatJSON, FileFormatYAML:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFileFormat, a.format)
+	}
+
+	return a, nil
+}
+
+// Path returns the path of the file.
+func (a *FileAdapter) Path() string {
+	return a.path
+}
+
+// LoadPolicy parses the whole file before loading the rules, a malformed file loads no rule.
+func (a *FileAdapter) LoadPolicy(model model.Model) error {
+	return a.LoadFilteredPolicy(model, nil)
+}
+
+// LoadFilteredPolicy loads the rules of the file selected by a Filter.
+func (a *FileAdapter) LoadFilteredPolicy(model model.Model, filter interface{}) error {
+	f, err := asFilter(filter)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if ok, err := f.loadSnapshot(model); ok {
+		a.filtered = true
+		return err
+	}
+
+	info, err := os.Stat(a.path)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(a.path)
+	if err != nil {
+		return err
+	}
+
+	rules, err := decodeRules(a.format, data)
+	if err != nil {
+		return fmt.Errorf("casbin: failed to parse policy file %s: %w", a.path, err)
+	}
+
+	if err = loadFilteredRules(model, rules, f); err != nil {
+		return err
+	}
+
+	a.modTime, a.size, a.filtered = info.ModTime(), info.Size(), f != nil
+
+	return nil
+}
+
+func (a *FileAdapter) IsFiltered() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.filtered
+}
+
+// SavePolicy replaces the file with the rules of the model.
+func (a *FileAdapter) SavePolicy(model model.Model) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	data, err := encodeRules(a.format, modelRules(model))
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(a.path), filepath.Base(a.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), a.path); err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(a.path); err == nil {
+		a.modTime, a.size = info.ModTime(), info.Size()
+	}
+
+	return nil
+}
+
+func (a *FileAdapter) AddPolicy(_ string, _ string, _ []string) error {
+	return errors.New("not implemented")
+}
+
+func (a *FileAdapter) RemovePolicy(_ string, _ string, _ []string) error {
+	return errors.New("not implemented")
+}
+
+func (a *FileAdapter) RemoveFilteredPolicy(_ string, _ string, _ int, _ ...string) error {
+	return errors.New("not implemented")
+}
+
+// fileVersion identifies a version of the file by its modification time and size.
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+// changed reports whether the file has been modified since it was last loaded or saved, and returns its current version.
+func (a *FileAdapter) changed() (fileVersion, bool, error) {
+	info, err := os.Stat(a.path)
+	if err != nil {
+		return fileVersion{}, false, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return fileVersion{info.ModTime(), info.Size()}, !info.ModTime().Equal(a.modTime) || info.Size() != a.size, nil
+}
+
+func decodeRules(format FileFormat, data []byte) ([]PolicyRule, error) {
+	var rules []PolicyRule
+
+	switch format {
+	case FileFormatJSON:
+		if err := json.Unmarshal(data, &rules); err != nil {
+			return nil, err
+		}
+
+	case FileFormatYAML:
+		if err := yaml.Unmarshal(data, &rules); err != nil {
+			return nil, err
+		}
+
+	case FileFormatCSV:
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.Comment = '#'
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		for {
+			record, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+				continue
+			}
+			for i := range record {
+				record[i] = strings.TrimSpace(record[i])
+			}
+			if len(record) > 7 {
+				line, _ := reader.FieldPos(0)
+				return nil, fmt.Errorf("line %d: too many fields", line)
+			}
+			rules = append(rules, newPolicyRule(record[0], record[1:]))
+		}
+	}
+
+	for i, rule := range rules {
+		if rule.PType == "" {
+			return nil, fmt.Errorf("rule %d: missing policy type", i+1)
+		}
+	}
+
+	return rules, nil
+}
+
+func encodeRules(format FileFormat, rules []PolicyRule) ([]byte, error) {
+	switch format {
+	case FileFormatJSON:
+		return json.MarshalIndent(rules, "", "  ")
+
+	case FileFormatYAML:
+		return yaml.Marshal(rules)
+	}
+
+	var buf bytes.Buffer
+	for _, rule := range rules {
+		fields := append([]string{rule.PType}, rule.values()...)
+		for i, field := range fields {
+			if strings.ContainsAny(field, ",\"\n") {
+				fields[i] = `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+			}
+		}
+		buf.WriteString(strings.Join(fields, ", "))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
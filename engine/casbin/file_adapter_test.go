@@ -0,0 +1,138 @@
+package casbin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+const testCSVPolicy = `# rules of the users service
+p, bobo, /api/users, GET, *
+p, alice, "/api/users,/api/orders", POST, *
+
+g, carol, bobo, *
+`
+
+const testJSONPolicy = `[
+  {"p_type": "p", "v0": "bobo", "v1": "/api/users", "v2": "GET", "v3": "*"},
+  {"p_type": "g", "v0": "carol", "v1": "bobo", "v2": "*"}
+]`
+
+const testYAMLPolicy = `- p_type: p
+  v0: bobo
+  v1: /api/users
+  v2: GET
+  v3: "*"
+- p_type: g
+  v0: carol
+  v1: bobo
+  v2: "*"
+`
+
+// writeFile replaces the file atomically, as the editors and git do.
+func writeFile(t *testing.T, path, content string) {
+	tmp := path + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, []byte(content), 0o600))
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+func TestFileAdapter(t *testing.T) {
+	cases := map[string]string{
+		"rules.csv":  testCSVPolicy,
+		"rules.json": testJSONPolicy,
+		"rules.yaml": testYAMLPolicy,
+	}
+
+	for name, content := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			writeFile(t, path, content)
+
+			adapter, err := NewFileAdapter(path)
+			require.NoError(t, err)
+
+			s, err := NewEngine(t.Context(), WithPolicyAdapter(adapter))
+			require.NoError(t, err)
+
+			for _, subject := range engine.MakeSubjects("bobo", "carol") {
+				allowed, err := s.IsAuthorized(t.Context(), subject, "GET", "/api/users", "")
+				assert.NoError(t, err)
+				assert.True(t, allowed)
+			}
+
+			// the rules survive a save and a reload
+			require.NoError(t, s.enforcer.SavePolicy())
+			require.NoError(t, s.enforcer.LoadPolicy())
+			allowed, err := s.IsAuthorized(t.Context(), "carol", "GET", "/api/users", "")
+			assert.NoError(t, err)
+			assert.True(t, allowed)
+		})
+	}
+
+	_, err := NewFileAdapter("rules.txt")
+	assert.ErrorIs(t, err, ErrUnknownFileFormat)
+
+	_, err = NewFileAdapter("rules.txt", WithFileFormat(FileFormatCSV))
+	assert.NoError(t, err)
+}
+
+func TestFileAdapter_QuotedCSV(t *testing.T) {
+	rules, err := decodeRules(FileFormatCSV, []byte(testCSVPolicy))
+	require.NoError(t, err)
+	assert.Equal(t, []PolicyRule{
+		{PType: "p", V0: "bobo", V1: "/api/users", V2: "GET", V3: "*"},
+		{PType: "p", V0: "alice", V1: "/api/users,/api/orders", V2: "POST", V3: "*"},
+		{PType: "g", V0: "carol", V1: "bobo", V2: "*"},
+	}, rules)
+
+	data, err := encodeRules(FileFormatCSV, rules)
+	require.NoError(t, err)
+	decoded, err := decodeRules(FileFormatCSV, data)
+	require.NoError(t, err)
+	assert.Equal(t, rules, decoded)
+
+	_, err = decodeRules(FileFormatCSV, []byte("p, a, b, c, d, e, f, g\n"))
+	assert.Error(t, err)
+}
+
+func TestPolicyWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.csv")
+	writeFile(t, path, "p, bobo, /api/users, GET, *\n")
+
+	adapter, err := NewFileAdapter(path)
+	require.NoError(t, err)
+
+	_, err = NewEngine(t.Context(), WithPolicyAdapter(newAdapter()), WithPolicyWatch(time.Millisecond))
+	assert.ErrorIs(t, err, ErrWatchNotSupported)
+
+	s, err := NewEngine(t.Context(), WithPolicyAdapter(adapter), WithPolicyWatch(5*time.Millisecond))
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	isAuthorized := func(subject engine.Subject) bool {
+		allowed, err := s.IsAuthorized(t.Context(), subject, "GET", "/api/users", "")
+		require.NoError(t, err)
+		return allowed
+	}
+	assert.True(t, isAuthorized("bobo"))
+	assert.False(t, isAuthorized("alice"))
+
+	writeFile(t, path, "p, alice, /api/users, GET, *\n# bobo has been removed\n")
+	assert.Eventually(t, func() bool {
+		return isAuthorized("alice") && !isAuthorized("bobo")
+	}, time.Second, 5*time.Millisecond)
+
+	// a malformed file keeps the last loaded policy
+	writeFile(t, path, "p, \"alice\n")
+	time.Sleep(50 * time.Millisecond)
+	assert.True(t, isAuthorized("alice"))
+
+	assert.NoError(t, s.Close())
+	assert.NoError(t, s.Close())
+}
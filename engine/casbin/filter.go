@@ -0,0 +1,185 @@
+package casbin
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+var (
+	ErrInvalidFilter = errors.New("casbin: the policy filter is not a casbin.Filter")
+	ErrNotFiltered   = errors.New("casbin: the policy is not filtered, see WithLoadedProjects")
+)
+
+// Filter selects the rules of a set of domains, the 4th field of the policy rules and the 3rd field
+// of the grouping rules. The rules of the wildcard domain, and the ones without domain, apply to
+// every domain and are always selected.
+type Filter struct {
+	Domains  []string
+	Wildcard string
+
+	// snapshot holds the rules already read by loadFiltered, the adapters of this package load
+	// them rather than reading their store again.
+	snapshot model.Model
+}
+
+// matches reports whether the rule belongs to one of the domains of the filter.
+func (f *Filter) matches(rule PolicyRule) bool {
+	var domain string
+	switch {
+	case strings.HasPrefix(rule.PType, "p"):
+		domain = rule.V3
+	case strings.HasPrefix(rule.PType, "g"):
+		domain = rule.V2
+	}
+	return domain == "" || domain == f.Wildcard || slices.Contains(f.Domains, domain)
+}
+
+// loadFilteredRules loads the rules matching the filter into the model.
+func loadFilteredRules(model model.Model, rules []PolicyRule, filter *Filter) error {
+	for _, rule := range rules {
+		if filter != nil && !filter.matches(rule) {
+			continue
+		}
+		if err := persist.LoadPolicyArray(append([]string{rule.PType}, rule.values()...), model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadSnapshot loads the rules of the snapshot of the filter into the model, it reports false when
+// the filter has no snapshot.
+func (f *Filter) loadSnapshot(model model.Model) (bool, error) {
+	if f == nil || f.snapshot == nil {
+		return false, nil
+	}
+	return true, loadFilteredRules(model, modelRules(f.snapshot), nil)
+}
+
+// asFilter converts the filter given to LoadFilteredPolicy, a nil filter selects every rule.
+func asFilter(filter interface{}) (*Filter, error) {
+	switch f := filter.(type) {
+	case nil:
+		return nil, nil
+	case *Filter:
+		return f, nil
+	case Filter:
+		return &f, nil
+	}
+	return nil, ErrInvalidFilter
+}
+
+// LoadedProjects returns the projects the rules are loaded for with WithLoadedProjects, it is
+// nil when every rule is loaded.
+func (s *State) LoadedProjects() engine.Projects {
+	s.filterMu.Lock()
+	defer s.filterMu.Unlock()
+
+	if s.filter == nil {
+		return nil
+	}
+
+	projects := make(engine.Projects, 0, len(s.filter.Domains))
+	for _, domain := range s.filter.Domains {
+		projects = append(projects, engine.Project(domain))
+	}
+	return projects
+}
+
+// LoadProjects loads the rules of the projects in addition to the loaded ones.
+func (s *State) LoadProjects(_ context.Context, projects ...engine.Project) error {
+	s.filterMu.Lock()
+	defer s.filterMu.Unlock()
+
+	if s.filter == nil {
+		return ErrNotFiltered
+	}
+
+	added := &Filter{Wildcard: s.wildcardItem}
+	for _, project := range projects {
+		if !slices.Contains(s.filter.Domains, string(project)) {
+			added.Domains = append(added.Domains, string(project))
+		}
+	}
+	if len(added.Domains) == 0 {
+		return nil
+	}
+
+	if err := s.enforcer.LoadIncrementalFilteredPolicy(added); err != nil {
+		s.log.Errorf("failed to load the policy of projects %v: %v", added.Domains, err)
+		return err
+	}
+
+	s.filter = &Filter{Domains: append(slices.Clone(s.filter.Domains), added.Domains...), Wildcard: s.wildcardItem}
+
+	return nil
+}
+
+// UnloadProjects drops the rules of the projects, the remaining ones are reloaded.
+func (s *State) UnloadProjects(_ context.Context, projects ...engine.Project) error {
+	s.filterMu.Lock()
+	defer s.filterMu.Unlock()
+
+	if s.filter == nil {
+		return ErrNotFiltered
+	}
+
+	filter := &Filter{Wildcard: s.wildcardItem}
+	for _, domain := range s.filter.Domains {
+		if !slices.Contains(projects, engine.Project(domain)) {
+			filter.Domains = append(filter.Domains, domain)
+		}
+	}
+
+	if err := s.loadFiltered(filter); err != nil {
+		s.log.Errorf("failed to reload the filtered policy: %v", err)
+		return err
+	}
+
+	s.filter = filter
+
+	return nil
+}
+
+// reloadPolicy reloads the enforcer rules from the adapter, only the loaded projects when filtered.
+func (s *State) reloadPolicy() error {
+	s.filterMu.Lock()
+	defer s.filterMu.Unlock()
+
+	if s.filter != nil {
+		return s.loadFiltered(s.filter)
+	}
+	return s.enforcer.LoadPolicy()
+}
+
+// loadFiltered replaces the rules of the enforcer with the ones selected by the filter. The enforcer
+// clears its rules before reading the adapter, so the rules are first read into a model of their own:
+// the loaded rules are kept when the adapter fails, on a malformed file for instance.
+func (s *State) loadFiltered(filter *Filter) error {
+	adapter, ok := s.policy.(persist.FilteredAdapter)
+	if !ok {
+		return s.enforcer.LoadFilteredPolicy(filter)
+	}
+
+	snapshot := s.definition.Copy()
+	if err := adapter.LoadFilteredPolicy(snapshot, filter); err != nil {
+		return err
+	}
+
+	return s.enforcer.LoadFilteredPolicy(&Filter{Domains: filter.Domains, Wildcard: filter.Wildcard, snapshot: snapshot})
+}
+
+// isLoaded reports whether the rules of the project are loaded.
+func (s *State) isLoaded(project engine.Project) bool {
+	s.filterMu.Lock()
+	defer s.filterMu.Unlock()
+
+	return s.filter == nil || slices.Contains(s.filter.Domains, string(project))
+}
@@ -0,0 +1,134 @@
+package casbin
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+func testFilteredPolicies() (engine.PolicyMap, engine.RoleMap) {
+	return engine.MakePolicyMap(
+			engine.Policy{
+				ID:      "pol-users",
+				Members: engine.MakeSubjects("bobo"),
+				Statements: engine.Statements{
+					{Effect: engine.EffectAllow, Resources: engine.MakeResources("/api/users"), Actions: engine.MakeActions("GET"), Projects: engine.MakeProjects("project1", "project2", "project3")},
+					{Effect: engine.EffectAllow, Resources: engine.MakeResources("/api/health"), Actions: engine.MakeActions("GET")},
+				},
+			},
+		),
+		engine.MakeRoleMap(nil, engine.RoleBindings{{Subject: "alice", Role: "bobo", Project: "project2"}})
+}
+
+func testFilteredEngine(t *testing.T, s *State) {
+	isAuthorized := func(subject engine.Subject, resource engine.Resource, project engine.Project) bool {
+		allowed, err := s.IsAuthorized(t.Context(), subject, "GET", resource, project)
+		require.NoError(t, err)
+		return allowed
+	}
+
+	assert.Equal(t, engine.MakeProjects("project1"), s.LoadedProjects())
+	assert.True(t, isAuthorized("bobo", "/api/users", "project1"))
+	assert.False(t, isAuthorized("bobo", "/api/users", "project2"))
+	assert.False(t, isAuthorized("alice", "/api/users", "project2"))
+	// the rules of every project are loaded
+	assert.True(t, isAuthorized("bobo", "/api/health", "project2"))
+
+	projects, err := s.FilterAuthorizedProjects(t.Context(), engine.MakeSubjects("bobo"))
+	require.NoError(t, err)
+	assert.Equal(t, engine.MakeProjects("project1"), projects)
+
+	require.NoError(t, s.LoadProjects(t.Context(), "project2", "project1"))
+	assert.ElementsMatch(t, engine.MakeProjects("project1", "project2"), s.LoadedProjects())
+	assert.True(t, isAuthorized("bobo", "/api/users", "project2"))
+	assert.True(t, isAuthorized("alice", "/api/users", "project2"))
+
+	require.NoError(t, s.UnloadProjects(t.Context(), "project1"))
+	assert.Equal(t, engine.MakeProjects("project2"), s.LoadedProjects())
+	assert.False(t, isAuthorized("bobo", "/api/users", "project1"))
+	assert.True(t, isAuthorized("bobo", "/api/users", "project2"))
+	assert.False(t, isAuthorized("bobo", "/api/users", "project3"))
+
+	projects, err = s.FilterAuthorizedProjects(t.Context(), engine.MakeSubjects("bobo"))
+	require.NoError(t, err)
+	assert.Equal(t, engine.MakeProjects("project2"), projects)
+}
+
+func TestLoadedProjects(t *testing.T) {
+	s, err := NewEngine(t.Context(), WithLoadedProjects(engine.MakeProjects("project1")))
+	require.NoError(t, err)
+
+	policies, roles := testFilteredPolicies()
+	require.NoError(t, s.SetPolicies(t.Context(), policies, roles))
+
+	testFilteredEngine(t, s)
+}
+
+func TestLoadedProjects_SQLAdapter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.db")
+
+	s, err := NewEngine(t.Context(), WithPolicyAdapter(newSQLiteAdapter(t, path)))
+	require.NoError(t, err)
+	policies, roles := testFilteredPolicies()
+	require.NoError(t, s.SetPolicies(t.Context(), policies, roles))
+
+	// the projects of the policies are known to the engine which has written them
+	s, err = NewEngine(t.Context(),
+		WithPolicyAdapter(newSQLiteAdapter(t, path)),
+		WithLoadedProjects(engine.MakeProjects("project1")),
+		WithProjects(engine.MakeProjects("project1", "project2", "project3")),
+	)
+	require.NoError(t, err)
+
+	testFilteredEngine(t, s)
+}
+
+func TestLoadedProjects_NotFiltered(t *testing.T) {
+	s, err := NewEngine(t.Context())
+	require.NoError(t, err)
+
+	assert.Nil(t, s.LoadedProjects())
+	assert.ErrorIs(t, s.LoadProjects(t.Context(), "project1"), ErrNotFiltered)
+	assert.ErrorIs(t, s.UnloadProjects(t.Context(), "project1"), ErrNotFiltered)
+}
+
+func TestLoadedProjects_CorruptReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.csv")
+	writeFile(t, path, "p, bobo, /api/users, GET, project1\np, bobo, /api/users, GET, project2\n")
+
+	adapter, err := NewFileAdapter(path)
+	require.NoError(t, err)
+
+	s, err := NewEngine(t.Context(),
+		WithPolicyAdapter(adapter),
+		WithLoadedProjects(engine.MakeProjects("project1", "project2")),
+		WithPolicyWatch(5*time.Millisecond),
+	)
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	isAuthorized := func(project engine.Project) bool {
+		allowed, err := s.IsAuthorized(t.Context(), "bobo", "GET", "/api/users", project)
+		require.NoError(t, err)
+		return allowed
+	}
+	assert.True(t, isAuthorized("project1"))
+	assert.True(t, isAuthorized("project2"))
+
+	// a malformed file keeps the loaded rules
+	writeFile(t, path, "p, \"bobo\n")
+	assert.Error(t, s.reloadPolicy())
+	time.Sleep(50 * time.Millisecond)
+	assert.True(t, isAuthorized("project1"))
+	assert.True(t, isAuthorized("project2"))
+
+	assert.Error(t, s.UnloadProjects(t.Context(), "project2"))
+	assert.Equal(t, engine.MakeProjects("project1", "project2"), s.LoadedProjects())
+	assert.True(t, isAuthorized("project1"))
+	assert.True(t, isAuthorized("project2"))
+}
@@ -1,7 +1,10 @@
 package casbin
 
 import (
+	"time"
+
 	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
 	"github.com/go-kratos/kratos/v2/log"
 
 	"github.com/tx7do/kratos-authz/engine"
@@ -55,7 +58,9 @@ func WithDefaultModel(name string) OptFunc {
 	}
 }
 
-func WithPolicyAdapter(policy *Adapter) OptFunc {
+// WithPolicyAdapter sets the adapter the rules are loaded from and saved to, such as the in-memory
+// Adapter or a SQLAdapter.
+func WithPolicyAdapter(policy persist.Adapter) OptFunc {
 	return func(s *State) {
 		s.policy = policy
 	}
@@ -66,10 +71,45 @@ func WithPolices(policies map[string]interface{}) OptFunc {
 		if s.policy == nil {
 			s.policy = newAdapter()
 		}
-		s.policy.SetPolicies(policies)
+		if adapter, ok := s.policy.(*Adapter); ok {
+			adapter.SetPolicies(policies)
+		} else {
+			s.initialPolicies = policies
+		}
+	}
+}
+
+// WithPolicyWatch polls the file of the FileAdapter at the given interval, and reloads the
+// enforcer when the file changes. A file which fails to load is logged and the last loaded
+// rules are kept. The watch is stopped by Close.
+func WithPolicyWatch(interval time.Duration) OptFunc {
+	return func(s *State) {
+		s.watchInterval = interval
+	}
+}
+
+// WithLoadedProjects loads only the rules of the projects, the domains of the rules, with an adapter
+// implementing persist.FilteredAdapter. The loaded projects can be changed with LoadProjects and
+// UnloadProjects.
+func WithLoadedProjects(projects engine.Projects) OptFunc {
+	return func(s *State) {
+		s.filter = &Filter{}
+		for _, project := range projects {
+			s.filter.Domains = append(s.filter.Domains, string(project))
+		}
+	}
+}
+
+// WithWatcher notifies the other instances of the policy changes made through the engine, and
+// applies their changes. The watcher is closed by Close.
+func WithWatcher(watcher *Watcher) OptFunc {
+	return func(s *State) {
+		s.watcher = watcher
 	}
 }
 
+// WithProjects restricts the projects returned by FilterAuthorizedProjects to the domains of the loaded
+// rules among projects. A rule of the wildcard domain authorizes each of the projects.
 func WithProjects(projects engine.Projects) OptFunc {
 	return func(s *State) {
 		s.projects = projects
@@ -0,0 +1,106 @@
+package casbin
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+// knownProjects returns the projects FilterAuthorizedProjects checks: the domains of the policy rules
+// and of the grouping rules, in the order of the rules, intersected with the ones given with WithProjects
+// when set. A rule of the wildcard domain or of a pattern domain covers every project given with WithProjects.
+// The rules are read in place under the lock of the synced enforcer, as GetAllDomains of the embedded
+// enforcer does not hold it while the policy is reloaded.
+func (s *State) knownProjects() engine.Projects {
+	lock := s.enforcer.GetLock()
+	lock.RLock()
+	defer lock.RUnlock()
+
+	var projects engine.Projects
+	seen := map[engine.Project]bool{}
+	pattern := false
+	add := func(sec, ptype string, index int) {
+		assertion, ok := s.enforcer.GetModel()[sec][ptype]
+		if !ok {
+			return
+		}
+		for _, rule := range assertion.Policy {
+			if len(rule) <= index {
+				continue
+			}
+			if s.isDomainPattern(rule[index]) {
+				pattern = true
+				continue
+			}
+			if project := engine.Project(rule[index]); !seen[project] {
+				seen[project] = true
+				projects = append(projects, project)
+			}
+		}
+	}
+	add("p", "p", 3)
+	add("g", "g", 2)
+
+	if len(s.projects) == 0 {
+		return projects
+	}
+	if pattern {
+		return s.projects
+	}
+	return slices.DeleteFunc(slices.Clone(s.projects), func(project engine.Project) bool {
+		return !seen[project]
+	})
+}
+
+// subjectDomains returns the domains of the policy rules of the subject and of its implicit roles,
+// and of the grouping rules of the subject, like GetDomainsForUser. all reports that one of the policy rules applies to
+// every project, through the wildcard or a pattern domain.
+func (s *State) subjectDomains(subject string) (domains []string, all bool, err error) {
+	groupings, err := s.enforcer.GetFilteredGroupingPolicy(0, subject)
+	if err != nil {
+		return nil, false, err
+	}
+
+	names := []string{subject}
+	for _, rule := range groupings {
+		if len(rule) <= 2 {
+			continue
+		}
+		domains = append(domains, rule[2])
+
+		roles, err := s.enforcer.GetImplicitRolesForUser(subject, rule[2])
+		if err != nil {
+			return nil, false, err
+		}
+		for _, role := range roles {
+			if !slices.Contains(names, role) {
+				names = append(names, role)
+			}
+		}
+	}
+
+	for _, name := range names {
+		policies, err := s.enforcer.GetFilteredPolicy(0, name)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, rule := range policies {
+			if len(rule) <= 3 {
+				continue
+			}
+			if s.isDomainPattern(rule[3]) {
+				return nil, true, nil
+			}
+			domains = append(domains, rule[3])
+		}
+	}
+
+	return domains, false, nil
+}
+
+// isDomainPattern reports whether the domain of a rule is the wildcard or a keyMatch pattern,
+// rather than a project.
+func (s *State) isDomainPattern(domain string) bool {
+	return domain == "" || domain == s.wildcardItem || strings.Contains(domain, "*")
+}
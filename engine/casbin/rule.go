@@ -1,18 +1,25 @@
 package casbin
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/casbin/casbin/v2/model"
 	"github.com/casbin/casbin/v2/persist"
+
+	"github.com/tx7do/kratos-authz/engine"
 )
 
+var ErrDenyNotSupported = errors.New("casbin: deny statements are not supported")
+
 type PolicyRule struct {
-	PType string `json:"p_type,omitempty"`
-	V0    string `json:"v0,omitempty"`
-	V1    string `json:"v1,omitempty"`
-	V2    string `json:"v2,omitempty"`
-	V3    string `json:"v3,omitempty"`
-	V4    string `json:"v4,omitempty"`
-	V5    string `json:"v5,omitempty"`
+	PType string `json:"p_type,omitempty" yaml:"p_type,omitempty"`
+	V0    string `json:"v0,omitempty" yaml:"v0,omitempty"`
+	V1    string `json:"v1,omitempty" yaml:"v1,omitempty"`
+	V2    string `json:"v2,omitempty" yaml:"v2,omitempty"`
+	V3    string `json:"v3,omitempty" yaml:"v3,omitempty"`
+	V4    string `json:"v4,omitempty" yaml:"v4,omitempty"`
+	V5    string `json:"v5,omitempty" yaml:"v5,omitempty"`
 }
 
 func (line PolicyRule) LoadPolicyLine(model model.Model) error {
@@ -37,3 +44,84 @@ func (line PolicyRule) LoadPolicyLine(model model.Model) error {
 	}
 	return persist.LoadPolicyLine(lineText, model)
 }
+
+// newPolicyRule creates the rule of the given type from its fields, the fields after the sixth are dropped.
+func newPolicyRule(ptype string, values []string) PolicyRule {
+	v := make([]string, 6)
+	copy(v, values)
+	return PolicyRule{PType: ptype, V0: v[0], V1: v[1], V2: v[2], V3: v[3], V4: v[4], V5: v[5]}
+}
+
+// matches reports whether the fields from fieldIndex match fieldValues, the empty values match any field.
+func (line PolicyRule) matches(fieldIndex int, fieldValues ...string) bool {
+	values := []string{line.V0, line.V1, line.V2, line.V3, line.V4, line.V5}
+	for i, value := range fieldValues {
+		if value == "" {
+			continue
+		}
+		if fieldIndex+i >= len(values) || values[fieldIndex+i] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// values returns the rule fields, without the trailing empty ones.
+func (line PolicyRule) values() []string {
+	values := []string{line.V0, line.V1, line.V2, line.V3, line.V4, line.V5}
+	for len(values) > 0 && values[len(values)-1] == "" {
+		values = values[:len(values)-1]
+	}
+	return values
+}
+
+// splitRules splits the rules into the policy ("p") and grouping ("g") rules expected by the enforcer.
+func splitRules(rules []PolicyRule) (policies [][]string, groupings [][]string) {
+	for _, rule := range rules {
+		switch rule.PType {
+		case "p":
+			policies = append(policies, rule.values())
+		case "g":
+			groupings = append(groupings, rule.values())
+		}
+	}
+	return policies, groupings
+}
+
+// rulesFromPolicies translates the engine-neutral policy model into policy ("p") and
+// grouping ("g") rules.
+func rulesFromPolicies(policies engine.Policies, roles engine.Roles, bindings engine.RoleBindings, wildcardItem string) ([]PolicyRule, error) {
+	var rules []PolicyRule
+
+	domain := func(project engine.Project) string {
+		if project == "" || project == engine.AllProjects {
+			return wildcardItem
+		}
+		return string(project)
+	}
+
+	roleActions := roles.RoleActions()
+	for _, policy := range policies {
+		for _, statement := range policy.Statements {
+			if statement.Effect != engine.EffectAllow {
+				return nil, fmt.Errorf("%w: policy %s", ErrDenyNotSupported, policy.ID)
+			}
+			for _, project := range statement.EffectiveProjects() {
+				dom := domain(project)
+				for _, member := range policy.Members {
+					for _, resource := range statement.Resources {
+						for _, action := range statement.EffectiveActions(roleActions) {
+							rules = append(rules, PolicyRule{PType: "p", V0: string(member), V1: string(resource), V2: string(action), V3: dom})
+						}
+					}
+				}
+			}
+		}
+	}
+
+	for _, binding := range bindings {
+		rules = append(rules, PolicyRule{PType: "g", V0: string(binding.Subject), V1: binding.Role, V2: domain(binding.Project)})
+	}
+
+	return rules, nil
+}
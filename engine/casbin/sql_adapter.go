@@ -0,0 +1,316 @@
+package casbin
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// DefaultSQLTableName is the table the SQLAdapter stores the rules in.
+const DefaultSQLTableName = "casbin_rule"
+
+// ErrFieldOutOfRange is returned for the filters with a field value past v5, the last rule column.
+var ErrFieldOutOfRange = errors.New("casbin: the filter has a field value past v5")
+
+var (
+	_ persist.BatchAdapter     = (*SQLAdapter)(nil)
+	_ persist.UpdatableAdapter = (*SQLAdapter)(nil)
+	_ persist.FilteredAdapter  = (*SQLAdapter)(nil)
+)
+
+type SQLAdapterOption func(*SQLAdapter)
+
+// WithSQLTableName sets the table the rules are stored in.
+func WithSQLTableName(name string) SQLAdapterOption {
+	return func(a *SQLAdapter) {
+		a.table = name
+	}
+}
+
+// WithSQLDollarPlaceholders uses the $1, $2... placeholders of PostgreSQL instead of ?.
+func WithSQLDollarPlaceholders() SQLAdapterOption {
+	return func(a *SQLAdapter) {
+		a.dollar = true
+	}
+}
+
+// SQLAdapter stores the rules in a database/sql table, with a column per rule field.
+type SQLAdapter struct {
+	db     *sql.DB
+	table  string
+	dollar bool
+
+	filtered atomic.Bool
+}
+
+// NewSQLAdapter creates the adapter, and its table when it does not exist.
+func NewSQLAdapter(db *sql.DB, opts ...SQLAdapterOption) (*SQLAdapter, error) {
+	a := &SQLAdapter{
+		db:    db,
+		table: DefaultSQLTableName,
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	if _, err := db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	p_type VARCHAR(32) NOT NULL DEFAULT '',
+	v0 VARCHAR(255) NOT NULL DEFAULT '',
+	v1 VARCHAR(255) NOT NULL DEFAULT '',
+	v2 VARCHAR(255) NOT NULL DEFAULT '',
+	v3 VARCHAR(255) NOT NULL DEFAULT '',
+	v4 VARCHAR(255) NOT NULL DEFAULT '',
+	v5 VARCHAR(255) NOT NULL DEFAULT ''
+)`, a.table)); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (a *SQLAdapter) LoadPolicy(model model.Model) error {
+	rules, err := a.query(a.db, "", nil)
+	if err != nil {
+		return err
+	}
+
+	a.filtered.Store(false)
+	return loadFilteredRules(model, rules, nil)
+}
+
+// LoadFilteredPolicy loads the rules selected by a Filter, only the rows of its domains are queried.
+func (a *SQLAdapter) LoadFilteredPolicy(model model.Model, filter interface{}) error {
+	f, err := asFilter(filter)
+	if err != nil {
+		return err
+	}
+	if f == nil {
+		return a.LoadPolicy(model)
+	}
+	if ok, err := f.loadSnapshot(model); ok {
+		a.filtered.Store(true)
+		return err
+	}
+
+	domains := append([]string{"", f.Wildcard}, f.Domains...)
+	args := make([]any, 0, 2*len(domains))
+	for range 2 {
+		for _, domain := range domains {
+			args = append(args, domain)
+		}
+	}
+	where := fmt.Sprintf(" WHERE (p_type LIKE 'p%%' AND v3 IN (%s)) OR (p_type LIKE 'g%%' AND v2 IN (%s))",
+		a.placeholders(1, len(domains)), a.placeholders(len(domains)+1, len(domains)))
+
+	rules, err := a.query(a.db, where, args)
+	if err != nil {
+		return err
+	}
+
+	a.filtered.Store(true)
+	return loadFilteredRules(model, rules, f)
+}
+
+func (a *SQLAdapter) IsFiltered() bool {
+	return a.filtered.Load()
+}
+
+// SavePolicy replaces the rules with the ones of the model.
+func (a *SQLAdapter) SavePolicy(model model.Model) error {
+	return a.transaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM " + a.table); err != nil {
+			return err
+		}
+		return a.insert(tx, modelRules(model))
+	})
+}
+
+func (a *SQLAdapter) AddPolicy(_ string, ptype string, rule []string) error {
+	return a.AddPolicies("", ptype, [][]string{rule})
+}
+
+func (a *SQLAdapter) RemovePolicy(_ string, ptype string, rule []string) error {
+	return a.RemovePolicies("", ptype, [][]string{rule})
+}
+
+func (a *SQLAdapter) AddPolicies(_ string, ptype string, rules [][]string) error {
+	return a.transaction(func(tx *sql.Tx) error {
+		return a.insert(tx, policyRules(ptype, rules))
+	})
+}
+
+func (a *SQLAdapter) RemovePolicies(_ string, ptype string, rules [][]string) error {
+	return a.transaction(func(tx *sql.Tx) error {
+		return a.delete(tx, policyRules(ptype, rules))
+	})
+}
+
+func (a *SQLAdapter) RemoveFilteredPolicy(_ string, ptype string, fieldIndex int, fieldValues ...string) error {
+	where, args, err := a.filter(ptype, fieldIndex, fieldValues...)
+	if err != nil {
+		return err
+	}
+	_, err = a.db.Exec("DELETE FROM "+a.table+where, args...)
+	return err
+}
+
+func (a *SQLAdapter) UpdatePolicy(_ string, ptype string, oldRule, newRule []string) error {
+	return a.UpdatePolicies("", ptype, [][]string{oldRule}, [][]string{newRule})
+}
+
+func (a *SQLAdapter) UpdatePolicies(_ string, ptype string, oldRules, newRules [][]string) error {
+	return a.transaction(func(tx *sql.Tx) error {
+		if err := a.delete(tx, policyRules(ptype, oldRules)); err != nil {
+			return err
+		}
+		return a.insert(tx, policyRules(ptype, newRules))
+	})
+}
+
+func (a *SQLAdapter) UpdateFilteredPolicies(_ string, ptype string, newRules [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	var oldRules [][]string
+	err := a.transaction(func(tx *sql.Tx) error {
+		where, args, err := a.filter(ptype, fieldIndex, fieldValues...)
+		if err != nil {
+			return err
+		}
+
+		rules, err := a.query(tx, where, args)
+		if err != nil {
+			return err
+		}
+		for _, rule := range rules {
+			oldRules = append(oldRules, rule.values())
+		}
+
+		if _, err = tx.Exec("DELETE FROM "+a.table+where, args...); err != nil {
+			return err
+		}
+		return a.insert(tx, policyRules(ptype, newRules))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return oldRules, nil
+}
+
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func (a *SQLAdapter) query(q queryer, where string, args []any) ([]PolicyRule, error) {
+	rows, err := q.Query("SELECT p_type, v0, v1, v2, v3, v4, v5 FROM "+a.table+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []PolicyRule
+	for rows.Next() {
+		var rule PolicyRule
+		if err = rows.Scan(&rule.PType, &rule.V0, &rule.V1, &rule.V2, &rule.V3, &rule.V4, &rule.V5); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (a *SQLAdapter) insert(tx *sql.Tx, rules []PolicyRule) error {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	stmt, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s (p_type, v0, v1, v2, v3, v4, v5) VALUES (%s)", a.table, a.placeholders(1, 7)))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, rule := range rules {
+		if _, err = stmt.Exec(rule.PType, rule.V0, rule.V1, rule.V2, rule.V3, rule.V4, rule.V5); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *SQLAdapter) delete(tx *sql.Tx, rules []PolicyRule) error {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	p := strings.Split(a.placeholders(1, 7), ", ")
+	stmt, err := tx.Prepare(fmt.Sprintf("DELETE FROM %s WHERE p_type = %s AND v0 = %s AND v1 = %s AND v2 = %s AND v3 = %s AND v4 = %s AND v5 = %s",
+		a.table, p[0], p[1], p[2], p[3], p[4], p[5], p[6]))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, rule := range rules {
+		if _, err = stmt.Exec(rule.PType, rule.V0, rule.V1, rule.V2, rule.V3, rule.V4, rule.V5); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filter returns the WHERE clause of the rules of type ptype matching the field values, the
+// empty values match any field.
+func (a *SQLAdapter) filter(ptype string, fieldIndex int, fieldValues ...string) (string, []any, error) {
+	conditions := []string{"p_type = " + a.placeholders(1, 1)}
+	args := []any{ptype}
+	for i, value := range fieldValues {
+		if value == "" {
+			continue
+		}
+		if fieldIndex+i < 0 || fieldIndex+i > 5 {
+			return "", nil, fmt.Errorf("%w: v%d", ErrFieldOutOfRange, fieldIndex+i)
+		}
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf("v%d = %s", fieldIndex+i, a.placeholders(len(args), 1)))
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
+// placeholders returns n comma separated placeholders, numbered from first with WithSQLDollarPlaceholders.
+func (a *SQLAdapter) placeholders(first, n int) string {
+	p := make([]string, n)
+	for i := range p {
+		if a.dollar {
+			p[i] = "$" + strconv.Itoa(first+i)
+		} else {
+			p[i] = "?"
+		}
+	}
+	return strings.Join(p, ", ")
+}
+
+func (a *SQLAdapter) transaction(fn func(tx *sql.Tx) error) error {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func policyRules(ptype string, rules [][]string) []PolicyRule {
+	result := make([]PolicyRule, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, newPolicyRule(ptype, rule))
+	}
+	return result
+}
@@ -0,0 +1,76 @@
+package casbin
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+func newSQLiteAdapter(t *testing.T, path string) *SQLAdapter {
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	adapter, err := NewSQLAdapter(db)
+	require.NoError(t, err)
+	return adapter
+}
+
+func TestSQLAdapter(t *testing.T) {
+	testAdapter(t, newSQLiteAdapter(t, filepath.Join(t.TempDir(), "rules.db")))
+}
+
+func TestSQLAdapter_FieldOutOfRange(t *testing.T) {
+	adapter := newSQLiteAdapter(t, filepath.Join(t.TempDir(), "rules.db"))
+	require.NoError(t, adapter.AddPolicy("", "p", []string{"bobo", "/api/users", "GET", "project1"}))
+
+	err := adapter.RemoveFilteredPolicy("", "p", 5, "", "bobo")
+	assert.ErrorIs(t, err, ErrFieldOutOfRange)
+	_, err = adapter.UpdateFilteredPolicies("", "p", nil, 6, "bobo")
+	assert.ErrorIs(t, err, ErrFieldOutOfRange)
+
+	// an empty value past v5 matches any field
+	require.NoError(t, adapter.RemoveFilteredPolicy("", "p", 0, "bobo", "", "", "", "", "", ""))
+	rules, err := adapter.query(adapter.db, "", nil)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+}
+
+func TestSQLAdapter_Engine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.db")
+
+	s, err := NewEngine(t.Context(), WithPolicyAdapter(newSQLiteAdapter(t, path)))
+	require.NoError(t, err)
+
+	err = s.SetPolicies(t.Context(),
+		engine.MakePolicyMap(engine.Policy{
+			ID:      "pol-bobo",
+			Members: engine.MakeSubjects("bobo"),
+			Statements: engine.Statements{
+				{Effect: engine.EffectAllow, Resources: engine.MakeResources("/api/users"), Actions: engine.MakeActions("GET")},
+			},
+		}),
+		nil,
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, s.AddRoleBindings(t.Context(), engine.RoleBindings{{Subject: "alice", Role: "bobo"}}))
+
+	// a new engine loads the rules saved by the first one
+	s, err = NewEngine(t.Context(), WithPolicyAdapter(newSQLiteAdapter(t, path)))
+	require.NoError(t, err)
+
+	allowed, err := s.IsAuthorized(t.Context(), "alice", "GET", "/api/users", "")
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, err = s.IsAuthorized(t.Context(), "alice", "POST", "/api/users", "")
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+}
@@ -0,0 +1,83 @@
+package casbin
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrWatchNotSupported = errors.New("casbin: the policy adapter is not a FileAdapter and cannot be watched")
+
+// filePoller polls the policy file and reloads the enforcer when it changes.
+type filePoller struct {
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+func (s *State) startWatch() error {
+	adapter, ok := s.policy.(*FileAdapter)
+	if !ok {
+		return ErrWatchNotSupported
+	}
+
+	w := &filePoller{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	s.poller = w
+
+	go func() {
+		defer close(w.done)
+
+		ticker := time.NewTicker(s.watchInterval)
+		defer ticker.Stop()
+
+		var seen fileVersion
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				seen = s.reloadFile(adapter, seen)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// reloadFile reloads the enforcer when the file has changed and has been left unchanged since the
+// previous poll, so that a file being written is not loaded. On failure the last loaded rules are kept.
+func (s *State) reloadFile(adapter *FileAdapter, seen fileVersion) fileVersion {
+	version, changed, err := adapter.changed()
+	if err != nil {
+		s.log.Errorf("failed to check policy file %s: %v", adapter.Path(), err)
+		return seen
+	}
+	if !changed || version != seen {
+		return version
+	}
+
+	if err = s.reloadPolicy(); err != nil {
+		s.log.Errorf("failed to reload policy file %s, keeping the last loaded policy: %v", adapter.Path(), err)
+		return version
+	}
+
+	s.log.Infof("reloaded policy file %s", adapter.Path())
+	return version
+}
+
+// Close stops watching the policy file, and closes the Watcher.
+func (s *State) Close() error {
+	if s.poller != nil {
+		s.poller.once.Do(func() {
+			close(s.poller.stop)
+		})
+		<-s.poller.done
+	}
+	if s.watcher != nil {
+		s.watcher.Close()
+	}
+	return nil
+}
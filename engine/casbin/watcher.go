@@ -0,0 +1,240 @@
+package casbin
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// WatcherTransport carries the policy change messages between the instances of a service.
+type WatcherTransport interface {
+	// Publish sends the message to the instances subscribed to the transport.
+	Publish(message []byte) error
+	// Subscribe calls handler with the published messages, the messages of the instance itself included.
+	Subscribe(handler func(message []byte)) error
+	Close() error
+}
+
+const (
+	watcherUpdate               = "update"
+	watcherSavePolicy           = "save_policy"
+	watcherAddPolicies          = "add_policies"
+	watcherRemovePolicies       = "remove_policies"
+	watcherRemoveFilteredPolicy = "remove_filtered_policy"
+	watcherUpdatePolicies       = "update_policies"
+)
+
+// watcherMessage describes a policy change, the rules are set for the incremental changes.
+type watcherMessage struct {
+	Origin      string       `json:"origin"`
+	Method      string       `json:"method"`
+	Sec         string       `json:"sec,omitempty"`
+	PType       string       `json:"ptype,omitempty"`
+	Rules       [][]string   `json:"rules,omitempty"`
+	NewRules    [][]string   `json:"new_rules,omitempty"`
+	FieldIndex  int          `json:"field_index,omitempty"`
+	FieldValues []string     `json:"field_values,omitempty"`
+	Policies    []PolicyRule `json:"policies,omitempty"`
+}
+
+var (
+	_ persist.WatcherEx        = (*Watcher)(nil)
+	_ persist.UpdatableWatcher = (*Watcher)(nil)
+)
+
+// Watcher notifies the other instances of the policy changes made through the engine or its
+// enforcer, over a WatcherTransport. It is given to an engine with WithWatcher.
+type Watcher struct {
+	id        string
+	transport WatcherTransport
+
+	mu       sync.RWMutex
+	callback func(string)
+	handler  func(*watcherMessage)
+}
+
+// NewWatcher creates a watcher and subscribes it to the transport.
+func NewWatcher(transport WatcherTransport) (*Watcher, error) {
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+
+	w := &Watcher{
+		id:        hex.EncodeToString(id[:]),
+		transport: transport,
+	}
+
+	if err := transport.Subscribe(w.receive); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// SetUpdateCallback sets the function called with the messages of the other instances, when the
+// watcher is not used by an engine.
+func (w *Watcher) SetUpdateCallback(callback func(string)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.callback = callback
+	return nil
+}
+
+func (w *Watcher) setHandler(handler func(*watcherMessage)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.handler = handler
+}
+
+func (w *Watcher) Update() error {
+	return w.publish(&watcherMessage{Method: watcherUpdate})
+}
+
+func (w *Watcher) UpdateForAddPolicy(sec, ptype string, params ...string) error {
+	return w.UpdateForAddPolicies(sec, ptype, params)
+}
+
+func (w *Watcher) UpdateForRemovePolicy(sec, ptype string, params ...string) error {
+	return w.UpdateForRemovePolicies(sec, ptype, params)
+}
+
+func (w *Watcher) UpdateForRemoveFilteredPolicy(sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	return w.publish(&watcherMessage{Method: watcherRemoveFilteredPolicy, Sec: sec, PType: ptype, FieldIndex: fieldIndex, FieldValues: fieldValues})
+}
+
+func (w *Watcher) UpdateForSavePolicy(model model.Model) error {
+	return w.publish(&watcherMessage{Method: watcherSavePolicy, Policies: modelRules(model)})
+}
+
+func (w *Watcher) UpdateForAddPolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(&watcherMessage{Method: watcherAddPolicies, Sec: sec, PType: ptype, Rules: rules})
+}
+
+func (w *Watcher) UpdateForRemovePolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(&watcherMessage{Method: watcherRemovePolicies, Sec: sec, PType: ptype, Rules: rules})
+}
+
+func (w *Watcher) UpdateForUpdatePolicy(sec string, ptype string, oldRule, newRule []string) error {
+	return w.UpdateForUpdatePolicies(sec, ptype, [][]string{oldRule}, [][]string{newRule})
+}
+
+func (w *Watcher) UpdateForUpdatePolicies(sec string, ptype string, oldRules, newRules [][]string) error {
+	return w.publish(&watcherMessage{Method: watcherUpdatePolicies, Sec: sec, PType: ptype, Rules: oldRules, NewRules: newRules})
+}
+
+// Close closes the transport.
+func (w *Watcher) Close() {
+	_ = w.transport.Close()
+}
+
+func (w *Watcher) publish(message *watcherMessage) error {
+	message.Origin = w.id
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return w.transport.Publish(data)
+}
+
+func (w *Watcher) receive(data []byte) {
+	var message watcherMessage
+	if err := json.Unmarshal(data, &message); err != nil || message.Origin == w.id {
+		return
+	}
+
+	w.mu.RLock()
+	handler, callback := w.handler, w.callback
+	w.mu.RUnlock()
+
+	if handler != nil {
+		handler(&message)
+	} else if callback != nil {
+		callback(string(data))
+	}
+}
+
+// applyWatcherMessage applies the change of another instance. The instances with an in-memory
+// Adapter apply the changes to their own rules, the ones sharing a store with the other instances
+// reload their rules from it.
+func (s *State) applyWatcherMessage(message *watcherMessage) {
+	adapter, ok := s.policy.(*Adapter)
+	if !ok || message.Method == watcherUpdate {
+		if err := s.reloadPolicy(); err != nil {
+			s.log.Errorf("failed to reload policy on watcher update: %v", err)
+		}
+		return
+	}
+
+	var err error
+	switch message.Method {
+	case watcherSavePolicy:
+		adapter.setRules(message.Policies)
+		err = s.reloadPolicy()
+	case watcherAddPolicies:
+		loaded, unloaded := s.splitLoadedRules(message.PType, message.Rules)
+		if len(loaded) > 0 {
+			_, err = s.enforcer.SelfAddPoliciesEx(message.Sec, message.PType, loaded)
+		}
+		if err == nil && len(unloaded) > 0 {
+			err = adapter.AddPolicies(message.Sec, message.PType, unloaded)
+		}
+	case watcherRemovePolicies:
+		loaded, unloaded := s.splitLoadedRules(message.PType, message.Rules)
+		if len(loaded) > 0 {
+			_, err = s.enforcer.SelfRemovePolicies(message.Sec, message.PType, loaded)
+		}
+		if err == nil && len(unloaded) > 0 {
+			err = adapter.RemovePolicies(message.Sec, message.PType, unloaded)
+		}
+	case watcherRemoveFilteredPolicy:
+		_, err = s.enforcer.SelfRemoveFilteredPolicy(message.Sec, message.PType, message.FieldIndex, message.FieldValues...)
+	case watcherUpdatePolicies:
+		_, err = s.enforcer.SelfUpdatePolicies(message.Sec, message.PType, message.Rules, message.NewRules)
+	}
+	if err != nil {
+		s.log.Errorf("failed to apply watcher %s message: %v", message.Method, err)
+	}
+}
+
+// splitLoadedRules splits the rules of the loaded projects, see WithLoadedProjects, from the other ones.
+func (s *State) splitLoadedRules(ptype string, rules [][]string) (loaded [][]string, unloaded [][]string) {
+	s.filterMu.Lock()
+	defer s.filterMu.Unlock()
+
+	if s.filter == nil {
+		return rules, nil
+	}
+
+	for _, rule := range rules {
+		if s.filter.matches(newPolicyRule(ptype, rule)) {
+			loaded = append(loaded, rule)
+		} else {
+			unloaded = append(unloaded, rule)
+		}
+	}
+	return loaded, unloaded
+}
+
+// notifyPolicies notifies the other instances that the whole policy has been replaced.
+func (s *State) notifyPolicies() {
+	if s.watcher == nil {
+		return
+	}
+
+	var err error
+	if adapter, ok := s.policy.(*Adapter); ok {
+		// the rules of the adapter, the enforcer only holds the loaded projects with WithLoadedProjects
+		err = s.watcher.publish(&watcherMessage{Method: watcherSavePolicy, Policies: adapter.allRules()})
+	} else {
+		err = s.watcher.Update()
+	}
+	if err != nil {
+		s.log.Errorf("failed to notify the policy update: %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+package casbin
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+func newWatchedEngine(t *testing.T, transport WatcherTransport, opts ...OptFunc) *State {
+	watcher, err := NewWatcher(transport)
+	require.NoError(t, err)
+
+	s, err := NewEngine(t.Context(), append(opts, WithWatcher(watcher))...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+
+	return s
+}
+
+func testWatchedEngines(t *testing.T, a, b *State, eventually func(func() bool)) {
+	isAuthorized := func(s *State, subject engine.Subject, action engine.Action, project engine.Project) bool {
+		allowed, err := s.IsAuthorized(t.Context(), subject, action, "/api/users", project)
+		require.NoError(t, err)
+		return allowed
+	}
+
+	policies, roles := testFilteredPolicies()
+	require.NoError(t, a.SetPolicies(t.Context(), policies, roles))
+	eventually(func() bool {
+		return isAuthorized(b, "bobo", "GET", "project1") && isAuthorized(b, "alice", "GET", "project2")
+	})
+
+	policy := engine.Policy{
+		ID:         "pol-carol",
+		Members:    engine.MakeSubjects("carol"),
+		Statements: engine.Statements{{Effect: engine.EffectAllow, Resources: engine.MakeResources("/api/users"), Actions: engine.MakeActions("POST")}},
+	}
+	require.NoError(t, a.AddPolicies(t.Context(), engine.Policies{policy}))
+	eventually(func() bool {
+		return isAuthorized(b, "carol", "POST", "")
+	})
+
+	bindings, _ := roles.RoleBindings()
+	require.NoError(t, a.RemoveRoleBindings(t.Context(), bindings))
+	eventually(func() bool {
+		return !isAuthorized(b, "alice", "GET", "project2")
+	})
+
+	_, err := a.enforcer.UpdatePolicy([]string{"carol", "/api/users", "POST", "*"}, []string{"carol", "/api/users", "DELETE", "*"})
+	require.NoError(t, err)
+	eventually(func() bool {
+		return !isAuthorized(b, "carol", "POST", "") && isAuthorized(b, "carol", "DELETE", "")
+	})
+
+	_, err = a.enforcer.RemoveFilteredPolicy(0, "carol")
+	require.NoError(t, err)
+	eventually(func() bool {
+		return !isAuthorized(b, "carol", "DELETE", "")
+	})
+
+	// the changes of b are applied to a as well
+	require.NoError(t, b.AddPolicies(t.Context(), engine.Policies{policy}))
+	eventually(func() bool {
+		return isAuthorized(a, "carol", "POST", "")
+	})
+}
+
+func TestWatcher_MemoryBus(t *testing.T) {
+	bus := NewMemoryBus()
+	a := newWatchedEngine(t, bus.Transport())
+	b := newWatchedEngine(t, bus.Transport())
+
+	testWatchedEngines(t, a, b, func(condition func() bool) {
+		assert.Eventually(t, condition, time.Second, time.Millisecond)
+	})
+}
+
+func TestWatcher_ConcurrentWriters(t *testing.T) {
+	bus := NewMemoryBus()
+	a := newWatchedEngine(t, bus.Transport())
+	b := newWatchedEngine(t, bus.Transport())
+
+	const writers, rules = 200, 300
+
+	policy := func(i int) engine.Policy {
+		resources := make(engine.Resources, 0, rules)
+		for j := 0; j < rules; j++ {
+			resources = append(resources, engine.Resource(fmt.Sprintf("/api/%d/%d", i, j)))
+		}
+		return engine.Policy{
+			ID:         fmt.Sprintf("pol-%d", i),
+			Members:    engine.MakeSubjects(engine.Subject(fmt.Sprintf("user%d", i))),
+			Statements: engine.Statements{{Effect: engine.EffectAllow, Resources: resources, Actions: engine.MakeActions("GET")}},
+		}
+	}
+
+	// both engines write at once, each one holding its lock while notifying the other
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		s := a
+		if i%2 == 1 {
+			s = b
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, s.AddPolicies(t.Context(), engine.Policies{policy(i)}))
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("concurrent writers are deadlocked")
+	}
+
+	for _, s := range []*State{a, b} {
+		assert.Eventually(t, func() bool {
+			p, err := s.enforcer.GetPolicy()
+			return err == nil && len(p) == writers*rules
+		}, 30*time.Second, 10*time.Millisecond)
+	}
+}
+
+func TestWatcher_SharedAdapter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.db")
+	bus := NewMemoryBus()
+	a := newWatchedEngine(t, bus.Transport(), WithPolicyAdapter(newSQLiteAdapter(t, path)))
+	b := newWatchedEngine(t, bus.Transport(), WithPolicyAdapter(newSQLiteAdapter(t, path)))
+
+	testWatchedEngines(t, a, b, func(condition func() bool) {
+		assert.Eventually(t, condition, time.Second, time.Millisecond)
+	})
+}
+
+func TestWatcher_UnixSocket(t *testing.T) {
+	dir := t.TempDir()
+
+	transport, err := NewUnixSocketTransport(dir)
+	require.NoError(t, err)
+	a := newWatchedEngine(t, transport)
+
+	transport, err = NewUnixSocketTransport(dir)
+	require.NoError(t, err)
+	b := newWatchedEngine(t, transport)
+
+	// the socket of an exited process
+	stale, err := NewUnixSocketTransport(dir)
+	require.NoError(t, err)
+	stale.listener.(interface{ SetUnlinkOnClose(bool) }).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	testWatchedEngines(t, a, b, func(condition func() bool) {
+		assert.Eventually(t, condition, time.Second, 5*time.Millisecond)
+	})
+
+	assert.NoFileExists(t, stale.path)
+}
+
+func TestWatcher_LoadedProjects(t *testing.T) {
+	bus := NewMemoryBus()
+	a := newWatchedEngine(t, bus.Transport())
+	b := newWatchedEngine(t, bus.Transport(), WithLoadedProjects(engine.MakeProjects("project1")))
+
+	policies, roles := testFilteredPolicies()
+	require.NoError(t, a.SetPolicies(t.Context(), policies, roles))
+	assert.Eventually(t, func() bool {
+		allowed, err := b.IsAuthorized(t.Context(), "bobo", "GET", "/api/users", "project1")
+		return err == nil && allowed
+	}, time.Second, time.Millisecond)
+
+	allowed, err := b.IsAuthorized(t.Context(), "bobo", "GET", "/api/users", "project2")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// the rules of the projects which are not loaded are kept by the adapter only
+	require.NoError(t, a.AddPolicies(t.Context(), engine.Policies{{
+		ID:         "pol-carol",
+		Members:    engine.MakeSubjects("carol"),
+		Statements: engine.Statements{{Effect: engine.EffectAllow, Resources: engine.MakeResources("/api/users"), Actions: engine.MakeActions("GET"), Projects: engine.MakeProjects("project1", "project2")}},
+	}}))
+	assert.Eventually(t, func() bool {
+		policy, _ := b.enforcer.GetFilteredPolicy(0, "carol")
+		return assert.ObjectsAreEqual([][]string{{"carol", "/api/users", "GET", "project1"}}, policy) &&
+			slices.Contains(b.policy.(*Adapter).allRules(), PolicyRule{PType: "p", V0: "carol", V1: "/api/users", V2: "GET", V3: "project2"})
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, b.LoadProjects(t.Context(), "project2"))
+	allowed, err = b.IsAuthorized(t.Context(), "carol", "GET", "/api/users", "project2")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+}
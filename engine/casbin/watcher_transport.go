@@ -0,0 +1,274 @@
+package casbin
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// messageQueue hands the messages to the handler of a subscriber in a goroutine of its own. The
+// publisher may hold the lock of its enforcer, and the handler takes the lock of another one: a
+// publisher waiting for the handlers would deadlock with a concurrent publisher of the other side.
+type messageQueue struct {
+	mu       sync.Mutex
+	messages [][]byte
+
+	ready chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+func newMessageQueue(handler func([]byte)) *messageQueue {
+	q := &messageQueue{
+		ready: make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go q.run(handler)
+	return q
+}
+
+// push queues the message without waiting for the handler.
+func (q *messageQueue) push(message []byte) {
+	q.mu.Lock()
+	q.messages = append(q.messages, message)
+	q.mu.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (q *messageQueue) run(handler func([]byte)) {
+	defer close(q.done)
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		case <-q.ready:
+		}
+
+		for {
+			q.mu.Lock()
+			if len(q.messages) == 0 {
+				q.mu.Unlock()
+				break
+			}
+			message := q.messages[0]
+			q.messages = q.messages[1:]
+			q.mu.Unlock()
+
+			handler(message)
+		}
+	}
+}
+
+// close drops the queued messages, and waits for the message being handled.
+func (q *messageQueue) close() {
+	close(q.stop)
+	<-q.done
+}
+
+// MemoryBus connects the watchers of the engines of a single process.
+type MemoryBus struct {
+	mu     sync.RWMutex
+	queues map[*memoryTransport]*messageQueue
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{queues: map[*memoryTransport]*messageQueue{}}
+}
+
+// Transport returns a new transport connected to the bus, to be given to NewWatcher.
+func (b *MemoryBus) Transport() WatcherTransport {
+	return &memoryTransport{bus: b}
+}
+
+type memoryTransport struct {
+	bus *MemoryBus
+}
+
+// Publish queues the message for every subscriber, which receive the messages in order.
+func (t *memoryTransport) Publish(message []byte) error {
+	t.bus.mu.RLock()
+	defer t.bus.mu.RUnlock()
+
+	for _, queue := range t.bus.queues {
+		queue.push(message)
+	}
+	return nil
+}
+
+func (t *memoryTransport) Subscribe(handler func([]byte)) error {
+	t.bus.mu.Lock()
+	defer t.bus.mu.Unlock()
+
+	if queue, ok := t.bus.queues[t]; ok {
+		queue.close()
+	}
+	t.bus.queues[t] = newMessageQueue(handler)
+	return nil
+}
+
+func (t *memoryTransport) Close() error {
+	t.bus.mu.Lock()
+	queue, ok := t.bus.queues[t]
+	delete(t.bus.queues, t)
+	t.bus.mu.Unlock()
+
+	if ok {
+		queue.close()
+	}
+	return nil
+}
+
+const (
+	unixSocketSuffix = ".sock"
+
+	// unixSocketReadTimeout bounds the time a connection is read for.
+	unixSocketReadTimeout = 5 * time.Second
+	// unixSocketMaxBackoff bounds the wait after a failed Accept.
+	unixSocketMaxBackoff = time.Second
+)
+
+// UnixSocketTransport connects the processes of a single host through Unix sockets in a shared
+// directory: every process listens on its own socket, and a message is sent to every socket of
+// the directory. The sockets of the processes which have exited are removed.
+type UnixSocketTransport struct {
+	dir      string
+	path     string
+	listener net.Listener
+
+	queue  *messageQueue
+	wg     sync.WaitGroup
+	closed chan struct{}
+	once   sync.Once
+}
+
+// NewUnixSocketTransport creates the socket of the process in dir, which is created if needed.
+// The path of the socket must not exceed the limit of the platform, about 100 characters.
+func NewUnixSocketTransport(dir string) (*UnixSocketTransport, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	var id [6]byte
+	_, _ = rand.Read(id[:])
+	path := filepath.Join(dir, hex.EncodeToString(id[:])+unixSocketSuffix)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UnixSocketTransport{
+		dir:      dir,
+		path:     path,
+		listener: listener,
+		closed:   make(chan struct{}),
+	}, nil
+}
+
+// Publish sends the message to the other sockets of the directory, the errors of the live sockets are joined.
+func (t *UnixSocketTransport) Publish(message []byte) error {
+	entries, err := os.ReadDir(t.dir)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), unixSocketSuffix) {
+			continue
+		}
+		path := filepath.Join(t.dir, entry.Name())
+		if path == t.path {
+			continue
+		}
+
+		if err = send(path, message); err != nil {
+			if errors.Is(err, syscall.ECONNREFUSED) {
+				// nobody listens on the socket anymore
+				_ = os.Remove(path)
+				continue
+			}
+			if !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func send(path string, message []byte) error {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return err
+	}
+	if _, err = conn.Write(message); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	return conn.Close()
+}
+
+// Subscribe calls handler with the messages received on the socket, one at a time.
+func (t *UnixSocketTransport) Subscribe(handler func([]byte)) error {
+	t.queue = newMessageQueue(handler)
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+
+		var backoff time.Duration
+		for {
+			conn, err := t.listener.Accept()
+			if err != nil {
+				if backoff == 0 {
+					backoff = 5 * time.Millisecond
+				} else {
+					backoff = min(2*backoff, unixSocketMaxBackoff)
+				}
+				select {
+				case <-t.closed:
+					return
+				case <-time.After(backoff):
+					continue
+				}
+			}
+			backoff = 0
+
+			_ = conn.SetReadDeadline(time.Now().Add(unixSocketReadTimeout))
+			message, err := io.ReadAll(conn)
+			_ = conn.Close()
+			if err == nil && len(message) > 0 {
+				t.queue.push(message)
+			}
+		}
+	}()
+	return nil
+}
+
+// Close stops receiving the messages and removes the socket.
+func (t *UnixSocketTransport) Close() error {
+	var err error
+	t.once.Do(func() {
+		close(t.closed)
+		err = t.listener.Close()
+		t.wg.Wait()
+		if t.queue != nil {
+			t.queue.close()
+		}
+	})
+	return err
+}
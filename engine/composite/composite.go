@@ -0,0 +1,483 @@
+package composite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+func init() {
+	_ = engine.Register(engine.Composite, func(ctx context.Context, options ...any) (engine.Engine, error) {
+		var opts []OptFunc
+		if len(options) > 0 {
+			for _, o := range options {
+				if opt, ok := o.(OptFunc); ok {
+					opts = append(opts, opt)
+				}
+			}
+		}
+
+		return NewEngine(ctx, opts...)
+	})
+}
+
+var (
+	ErrNoEngines       = errors.New("composite: no engines configured")
+	ErrUnknownStrategy = errors.New("composite: unknown combining strategy")
+)
+
+var _ engine.Engine = (*State)(nil)
+
+type route struct {
+	prefix string
+	engine engine.Engine
+}
+
+// State combines the decisions of several engines with a combining Strategy.
+type State struct {
+	engines  []engine.Engine
+	routes   []route
+	strategy Strategy
+}
+
+func NewEngine(_ context.Context, opts ...OptFunc) (*State, error) {
+	s := &State{
+		strategy: DenyOverrides,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	switch s.strategy {
+	case FirstApplicable, DenyOverrides, PermitOverrides:
+		if len(s.engines) == 0 {
+			return nil, ErrNoEngines
+		}
+
+	case ResourcePrefix:
+		if len(s.routes) == 0 {
+			return nil, ErrNoEngines
+		}
+		// longest prefix first
+		sort.SliceStable(s.routes, func(i, j int) bool {
+			return len(s.routes[i].prefix) > len(s.routes[j].prefix)
+		})
+		for _, r := range s.routes {
+			if !containsEngine(s.engines, r.engine) {
+				s.engines = append(s.engines, r.engine)
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStrategy, s.strategy)
+	}
+
+	return s, nil
+}
+
+func (s *State) Name() string {
+	return string(engine.Composite)
+}
+
+func (s *State) ProjectsAuthorized(ctx context.Context, subjects engine.Subjects, action engine.Action, resource engine.Resource, projects engine.Projects) (engine.Projects, error) {
+	if s.strategy == ResourcePrefix {
+		e := s.route(resource)
+		if e == nil {
+			return engine.Projects{}, nil
+		}
+		return e.ProjectsAuthorized(ctx, subjects, action, resource, projects)
+	}
+
+	results := make([]engine.Projects, 0, len(s.engines))
+	for _, e := range s.engines {
+		r, err := e.ProjectsAuthorized(ctx, subjects, action, resource, projects)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+
+	return combine(s.strategy, results), nil
+}
+
+func (s *State) FilterAuthorizedPairs(ctx context.Context, subjects engine.Subjects, pairs engine.Pairs) (engine.Pairs, error) {
+	if s.strategy == ResourcePrefix {
+		return s.filterRoutedPairs(ctx, subjects, pairs)
+	}
+
+	results := make([]engine.Pairs, 0, len(s.engines))
+	for _, e := range s.engines {
+		r, err := e.FilterAuthorizedPairs(ctx, subjects, pairs)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+
+	return combine(s.strategy, results), nil
+}
+
+func (s *State) FilterAuthorizedProjects(ctx context.Context, subjects engine.Subjects) (engine.Projects, error) {
+	results := make([]engine.Projects, 0, len(s.engines))
+	for _, e := range s.engines {
+		r, err := e.FilterAuthorizedProjects(ctx, subjects)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+
+	if s.strategy == ResourcePrefix {
+		// without a resource every routed engine may contribute projects
+		return union(results), nil
+	}
+
+	return combine(s.strategy, results), nil
+}
+
+func (s *State) IsAuthorized(ctx context.Context, subject engine.Subject, action engine.Action, resource engine.Resource, project engine.Project) (bool, error) {
+	decision, err := s.Decide(ctx, subject, action, resource, project)
+	if err != nil {
+		return false, err
+	}
+	return decision.Allowed(), nil
+}
+
+func (s *State) Decide(ctx context.Context, subject engine.Subject, action engine.Action, resource engine.Resource, project engine.Project) (*engine.Decision, error) {
+	start := time.Now()
+
+	var (
+		decision *engine.Decision
+		err      error
+	)
+	switch s.strategy {
+	case ResourcePrefix:
+		decision, err = s.decideRouted(ctx, subject, action, resource, project)
+	case FirstApplicable:
+		decision, err = s.decideFirstApplicable(ctx, subject, action, resource, project)
+	case PermitOverrides:
+		decision, err = s.decideOverrides(ctx, engine.EffectAllow, subject, action, resource, project)
+	default:
+		decision, err = s.decideOverrides(ctx, engine.EffectDeny, subject, action, resource, project)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	decision.Duration = time.Since(start)
+
+	return decision, nil
+}
+
+func (s *State) BatchIsAuthorized(ctx context.Context, requests engine.Requests) ([]bool, error) {
+	switch s.strategy {
+	case ResourcePrefix:
+		return s.batchRouted(ctx, requests)
+
+	case FirstApplicable:
+		// applicability can only be told from a decision
+		result := make([]bool, len(requests))
+		for i, r := range requests {
+			decision, err := s.decideFirstApplicable(ctx, r.Subject, r.Action, r.Resource, r.Project)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = decision.Allowed()
+		}
+		return result, nil
+
+	default:
+		result := make([]bool, len(requests))
+		for i := range result {
+			result[i] = s.strategy == DenyOverrides
+		}
+		for _, e := range s.engines {
+			allowed, err := e.BatchIsAuthorized(ctx, requests)
+			if err != nil {
+				return nil, err
+			}
+			for i := range result {
+				if s.strategy == DenyOverrides {
+					result[i] = result[i] && allowed[i]
+				} else {
+					result[i] = result[i] || allowed[i]
+				}
+			}
+		}
+		return result, nil
+	}
+}
+
+// SetPolicies fans the policies out to every wrapped engine.
+func (s *State) SetPolicies(ctx context.Context, policies engine.PolicyMap, roles engine.RoleMap) error {
+	return s.fanOut(func(e engine.Engine) error {
+		return e.SetPolicies(ctx, policies, roles)
+	})
+}
+
+// AddPolicies fans the policies out to every wrapped engine.
+func (s *State) AddPolicies(ctx context.Context, policies engine.Policies) error {
+	return s.fanOut(func(e engine.Engine) error {
+		return e.AddPolicies(ctx, policies)
+	})
+}
+
+// RemovePolicies fans the policies out to every wrapped engine.
+func (s *State) RemovePolicies(ctx context.Context, policies engine.Policies) error {
+	return s.fanOut(func(e engine.Engine) error {
+		return e.RemovePolicies(ctx, policies)
+	})
+}
+
+// AddRoleBindings fans the bindings out to every wrapped engine.
+func (s *State) AddRoleBindings(ctx context.Context, bindings engine.RoleBindings) error {
+	return s.fanOut(func(e engine.Engine) error {
+		return e.AddRoleBindings(ctx, bindings)
+	})
+}
+
+// RemoveRoleBindings fans the bindings out to every wrapped engine.
+func (s *State) RemoveRoleBindings(ctx context.Context, bindings engine.RoleBindings) error {
+	return s.fanOut(func(e engine.Engine) error {
+		return e.RemoveRoleBindings(ctx, bindings)
+	})
+}
+
+func (s *State) fanOut(write func(e engine.Engine) error) error {
+	var errs []error
+	for _, e := range s.engines {
+		if err := write(e); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", e.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *State) decideOverrides(ctx context.Context, overriding engine.Effect, subject engine.Subject, action engine.Action, resource engine.Resource, project engine.Project) (*engine.Decision, error) {
+	var reasons []string
+	var matched []string
+	for _, e := range s.engines {
+		d, err := e.Decide(ctx, subject, action, resource, project)
+		if err != nil {
+			return nil, err
+		}
+
+		if d.Effect == overriding {
+			return s.wrapDecision(e, d), nil
+		}
+
+		if d.Allowed() {
+			matched = append(matched, prefixed(e, d.MatchedPolicies)...)
+		} else {
+			reasons = append(reasons, e.Name()+": "+d.Reason)
+		}
+	}
+
+	if overriding == engine.EffectDeny {
+		return engine.MakeAllowDecision(s.Name(), matched...), nil
+	}
+
+	return engine.MakeDenyDecision(s.Name(), strings.Join(reasons, "; ")), nil
+}
+
+func (s *State) decideFirstApplicable(ctx context.Context, subject engine.Subject, action engine.Action, resource engine.Resource, project engine.Project) (*engine.Decision, error) {
+	for _, e := range s.engines {
+		d, err := e.Decide(ctx, subject, action, resource, project)
+		if err != nil {
+			return nil, err
+		}
+
+		// a deny without any matched policy means the engine has nothing to say about the request
+		if d.Allowed() || len(d.MatchedPolicies) > 0 {
+			return s.wrapDecision(e, d), nil
+		}
+	}
+
+	return engine.MakeDenyDecision(s.Name(), "no applicable engine"), nil
+}
+
+func (s *State) decideRouted(ctx context.Context, subject engine.Subject, action engine.Action, resource engine.Resource, project engine.Project) (*engine.Decision, error) {
+	e := s.route(resource)
+	if e == nil {
+		return engine.MakeDenyDecision(s.Name(), fmt.Sprintf("no engine routed for resource %q", resource)), nil
+	}
+
+	d, err := e.Decide(ctx, subject, action, resource, project)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.wrapDecision(e, d), nil
+}
+
+func (s *State) batchRouted(ctx context.Context, requests engine.Requests) ([]bool, error) {
+	result := make([]bool, len(requests))
+
+	groups := map[engine.Engine][]int{}
+	var order []engine.Engine
+	for i, r := range requests {
+		e := s.route(r.Resource)
+		if e == nil {
+			continue
+		}
+		if _, ok := groups[e]; !ok {
+			order = append(order, e)
+		}
+		groups[e] = append(groups[e], i)
+	}
+
+	for _, e := range order {
+		indices := groups[e]
+		batch := make(engine.Requests, 0, len(indices))
+		for _, i := range indices {
+			batch = append(batch, requests[i])
+		}
+
+		allowed, err := e.BatchIsAuthorized(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range indices {
+			result[i] = allowed[j]
+		}
+	}
+
+	return result, nil
+}
+
+func (s *State) filterRoutedPairs(ctx context.Context, subjects engine.Subjects, pairs engine.Pairs) (engine.Pairs, error) {
+	groups := map[engine.Engine]engine.Pairs{}
+	var order []engine.Engine
+	for _, p := range pairs {
+		e := s.route(p.Resource)
+		if e == nil {
+			continue
+		}
+		if _, ok := groups[e]; !ok {
+			order = append(order, e)
+		}
+		groups[e] = append(groups[e], p)
+	}
+
+	allowed := map[engine.Pair]bool{}
+	for _, e := range order {
+		r, err := e.FilterAuthorizedPairs(ctx, subjects, groups[e])
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range r {
+			allowed[p] = true
+		}
+	}
+
+	result := make(engine.Pairs, 0, len(allowed))
+	for _, p := range pairs {
+		if allowed[p] {
+			result = append(result, p)
+			delete(allowed, p)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *State) route(resource engine.Resource) engine.Engine {
+	for _, r := range s.routes {
+		if strings.HasPrefix(string(resource), r.prefix) {
+			return r.engine
+		}
+	}
+	return nil
+}
+
+func (s *State) wrapDecision(e engine.Engine, d *engine.Decision) *engine.Decision {
+	return &engine.Decision{
+		Effect:            d.Effect,
+		Engine:            s.Name(),
+		MatchedPolicies:   prefixed(e, d.MatchedPolicies),
+		MatchedStatements: prefixed(e, d.MatchedStatements),
+		Reason:            d.Reason,
+	}
+}
+
+func combine[S ~[]T, T comparable](strategy Strategy, results []S) S {
+	switch strategy {
+	case FirstApplicable:
+		for _, r := range results {
+			if len(r) > 0 {
+				return r
+			}
+		}
+		return S{}
+	case PermitOverrides:
+		return union(results)
+	default:
+		return intersection(results)
+	}
+}
+
+func union[S ~[]T, T comparable](results []S) S {
+	seen := map[T]bool{}
+	out := make(S, 0)
+	for _, r := range results {
+		for _, v := range r {
+			if !seen[v] {
+				seen[v] = true
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+func intersection[S ~[]T, T comparable](results []S) S {
+	out := make(S, 0)
+	if len(results) == 0 {
+		return out
+	}
+
+	counts := map[T]int{}
+	for _, r := range results {
+		seen := map[T]bool{}
+		for _, v := range r {
+			if !seen[v] {
+				seen[v] = true
+				counts[v]++
+			}
+		}
+	}
+
+	for _, v := range results[0] {
+		if counts[v] == len(results) {
+			out = append(out, v)
+			counts[v] = 0
+		}
+	}
+	return out
+}
+
+func prefixed(e engine.Engine, ids []string) []string {
+	if len(ids) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, e.Name()+":"+id)
+	}
+	return out
+}
+
+func containsEngine(engines []engine.Engine, e engine.Engine) bool {
+	for _, x := range engines {
+		if x == e {
+			return true
+		}
+	}
+	return false
+}
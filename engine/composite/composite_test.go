@@ -0,0 +1,232 @@
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+// staticEngine allows the resources it has been given, and explicitly denies the ones prefixed with "!".
+type staticEngine struct {
+	name      string
+	resources map[engine.Resource]engine.Effect
+	projects  engine.Projects
+	policies  engine.PolicyMap
+	added     engine.Policies
+}
+
+func newStaticEngine(name string, resources ...string) *staticEngine {
+	e := &staticEngine{name: name, resources: map[engine.Resource]engine.Effect{}}
+	for _, r := range resources {
+		if r[0] == '!' {
+			e.resources[engine.Resource(r[1:])] = engine.EffectDeny
+		} else {
+			e.resources[engine.Resource(r)] = engine.EffectAllow
+		}
+	}
+	return e
+}
+
+func (e *staticEngine) Name() string {
+	return e.name
+}
+
+func (e *staticEngine) ProjectsAuthorized(_ context.Context, _ engine.Subjects, _ engine.Action, resource engine.Resource, projects engine.Projects) (engine.Projects, error) {
+	if e.resources[resource] == engine.EffectAllow {
+		return projects, nil
+	}
+	return engine.Projects{}, nil
+}
+
+func (e *staticEngine) FilterAuthorizedPairs(_ context.Context, _ engine.Subjects, pairs engine.Pairs) (engine.Pairs, error) {
+	result := engine.Pairs{}
+	for _, p := range pairs {
+		if e.resources[p.Resource] == engine.EffectAllow {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+func (e *staticEngine) FilterAuthorizedProjects(_ context.Context, _ engine.Subjects) (engine.Projects, error) {
+	return e.projects, nil
+}
+
+func (e *staticEngine) IsAuthorized(ctx context.Context, subject engine.Subject, action engine.Action, resource engine.Resource, project engine.Project) (bool, error) {
+	d, err := e.Decide(ctx, subject, action, resource, project)
+	return d.Allowed(), err
+}
+
+func (e *staticEngine) Decide(_ context.Context, _ engine.Subject, _ engine.Action, resource engine.Resource, _ engine.Project) (*engine.Decision, error) {
+	switch e.resources[resource] {
+	case engine.EffectAllow:
+		return engine.MakeAllowDecision(e.name, string(resource)), nil
+	case engine.EffectDeny:
+		return engine.MakeDenyDecision(e.name, "denied", string(resource)), nil
+	default:
+		return engine.MakeDenyDecision(e.name, "no match"), nil
+	}
+}
+
+func (e *staticEngine) BatchIsAuthorized(_ context.Context, requests engine.Requests) ([]bool, error) {
+	result := make([]bool, len(requests))
+	for i, r := range requests {
+		result[i] = e.resources[r.Resource] == engine.EffectAllow
+	}
+	return result, nil
+}
+
+func (e *staticEngine) SetPolicies(_ context.Context, policies engine.PolicyMap, _ engine.RoleMap) error {
+	e.policies = policies
+	return nil
+}
+
+func (e *staticEngine) AddPolicies(_ context.Context, policies engine.Policies) error {
+	e.added = append(e.added, policies...)
+	return nil
+}
+
+func (e *staticEngine) RemovePolicies(_ context.Context, _ engine.Policies) error {
+	return nil
+}
+
+func (e *staticEngine) AddRoleBindings(_ context.Context, _ engine.RoleBindings) error {
+	return nil
+}
+
+func (e *staticEngine) RemoveRoleBindings(_ context.Context, _ engine.RoleBindings) error {
+	return nil
+}
+
+func TestStrategies(t *testing.T) {
+	casbin := newStaticEngine("casbin", "a", "b", "!c")
+	opa := newStaticEngine("opa", "b", "c", "!d")
+
+	tests := []struct {
+		strategy Strategy
+		allowed  map[engine.Resource]bool
+	}{
+		{
+			strategy: DenyOverrides,
+			allowed:  map[engine.Resource]bool{"a": false, "b": true, "c": false, "d": false, "e": false},
+		},
+		{
+			strategy: PermitOverrides,
+			allowed:  map[engine.Resource]bool{"a": true, "b": true, "c": true, "d": false, "e": false},
+		},
+		{
+			strategy: FirstApplicable,
+			allowed:  map[engine.Resource]bool{"a": true, "b": true, "c": false, "d": false, "e": false},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.strategy), func(t *testing.T) {
+			s, err := NewEngine(t.Context(), WithStrategy(test.strategy), WithEngines(casbin, opa))
+			assert.Nil(t, err)
+
+			var requests engine.Requests
+			var expected []bool
+			for res, allowed := range test.allowed {
+				d, err := s.Decide(t.Context(), "bobo", "GET", res, "")
+				assert.Nil(t, err)
+				assert.Equalf(t, allowed, d.Allowed(), "resource %s", res)
+				assert.Equal(t, string(engine.Composite), d.Engine)
+
+				requests = append(requests, engine.MakeRequest("bobo", "GET", res, ""))
+				expected = append(expected, allowed)
+			}
+
+			r, err := s.BatchIsAuthorized(t.Context(), requests)
+			assert.Nil(t, err)
+			assert.Equal(t, expected, r)
+		})
+	}
+}
+
+func TestResourcePrefix(t *testing.T) {
+	casbin := newStaticEngine("casbin", "/api/users")
+	zanzibar := newStaticEngine("zanzibar", "document:Z")
+
+	s, err := NewEngine(t.Context(),
+		WithStrategy(ResourcePrefix),
+		WithRoute("/api/", casbin),
+		WithRoute("document:", zanzibar),
+	)
+	assert.Nil(t, err)
+
+	d, err := s.Decide(t.Context(), "bobo", "GET", "document:Z", "")
+	assert.Nil(t, err)
+	assert.True(t, d.Allowed())
+	assert.Equal(t, []string{"zanzibar:document:Z"}, d.MatchedPolicies)
+
+	d, err = s.Decide(t.Context(), "bobo", "GET", "folder:Z", "")
+	assert.Nil(t, err)
+	assert.False(t, d.Allowed())
+
+	pairs := engine.MakePairs(
+		engine.MakePair("document:Z", "reader"),
+		engine.MakePair("/api/users", "GET"),
+		engine.MakePair("/api/dept", "GET"),
+		engine.MakePair("document:Z", "reader"),
+	)
+	r, err := s.FilterAuthorizedPairs(t.Context(), engine.MakeSubjects("bobo"), pairs)
+	assert.Nil(t, err)
+	assert.Equal(t, engine.Pairs{engine.MakePair("document:Z", "reader"), engine.MakePair("/api/users", "GET")}, r)
+
+	allowed, err := s.BatchIsAuthorized(t.Context(), engine.MakeRequests(
+		engine.MakeRequest("bobo", "GET", "/api/users", ""),
+		engine.MakeRequest("bobo", "reader", "document:Z", ""),
+		engine.MakeRequest("bobo", "reader", "folder:Z", ""),
+	))
+	assert.Nil(t, err)
+	assert.Equal(t, []bool{true, true, false}, allowed)
+}
+
+func TestSetPoliciesFanOut(t *testing.T) {
+	casbin := newStaticEngine("casbin")
+	opa := newStaticEngine("opa")
+
+	s, err := engine.NewEngine(t.Context(), engine.Composite, WithEngines(casbin, opa))
+	assert.Nil(t, err)
+
+	policies := engine.PolicyMap{"policies": "p"}
+	assert.Nil(t, s.SetPolicies(t.Context(), policies, nil))
+	assert.Equal(t, policies, casbin.policies)
+	assert.Equal(t, policies, opa.policies)
+
+	added := engine.Policies{{ID: "p1", Members: engine.MakeSubjects("bobo")}}
+	assert.Nil(t, s.AddPolicies(t.Context(), added))
+	assert.Equal(t, added, casbin.added)
+	assert.Equal(t, added, opa.added)
+}
+
+func TestCombineSets(t *testing.T) {
+	casbin := newStaticEngine("casbin")
+	casbin.projects = engine.MakeProjects("p1", "p2")
+	opa := newStaticEngine("opa")
+	opa.projects = engine.MakeProjects("p2", "p3")
+
+	tests := []struct {
+		strategy Strategy
+		equal    engine.Projects
+	}{
+		{strategy: DenyOverrides, equal: engine.Projects{"p2"}},
+		{strategy: PermitOverrides, equal: engine.Projects{"p1", "p2", "p3"}},
+		{strategy: FirstApplicable, equal: engine.Projects{"p1", "p2"}},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.strategy), func(t *testing.T) {
+			s, err := NewEngine(t.Context(), WithStrategy(test.strategy), WithEngines(casbin, opa))
+			assert.Nil(t, err)
+
+			r, err := s.FilterAuthorizedProjects(t.Context(), engine.MakeSubjects("bobo"))
+			assert.Nil(t, err)
+			assert.Equal(t, test.equal, r)
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package composite
+
+import (
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+type Strategy string
+
+const (
+	// FirstApplicable lets the first engine which has a policy matching the request decide.
+	FirstApplicable Strategy = "first-applicable"
+	// DenyOverrides requires every engine to allow the request.
+	DenyOverrides Strategy = "deny-overrides"
+	// PermitOverrides allows the request as soon as one engine allows it.
+	PermitOverrides Strategy = "permit-overrides"
+	// ResourcePrefix sends the request to the engine routed to the longest matching resource prefix.
+	ResourcePrefix Strategy = "resource-prefix"
+)
+
+type OptFunc func(*State)
+
+// WithEngines appends engines which are combined in the given order.
+func WithEngines(engines ...engine.Engine) OptFunc {
+	return func(s *State) {
+		s.engines = append(s.engines, engines...)
+	}
+}
+
+// WithStrategy sets the combining algorithm, it defaults to DenyOverrides.
+func WithStrategy(strategy Strategy) OptFunc {
+	return func(s *State) {
+		s.strategy = strategy
+	}
+}
+
+// WithRoute routes the resources starting with prefix to e when using the ResourcePrefix strategy.
+// The empty prefix acts as the default route.
+func WithRoute(prefix string, e engine.Engine) OptFunc {
+	return func(s *State) {
+		s.routes = append(s.routes, route{prefix: prefix, engine: e})
+	}
+}
@@ -3,8 +3,9 @@ package engine
 type Type string
 
 const (
-	Noop     Type = "noop"
-	Casbin   Type = "casbin"
-	Opa      Type = "opa"
-	Zanzibar Type = "zanzibar"
+	Noop      Type = "noop"
+	Casbin    Type = "casbin"
+	Opa       Type = "opa"
+	Zanzibar  Type = "zanzibar"
+	Composite Type = "composite"
 )
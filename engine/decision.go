@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"time"
+)
+
+type Effect string
+
+const (
+	EffectAllow Effect = "allow"
+	EffectDeny  Effect = "deny"
+)
+
+// Decision describes the outcome of a single authorization check together with the
+// information an engine used to reach it.
+type Decision struct {
+	Effect Effect `json:"effect"`
+	Engine string `json:"engine"`
+
+	// MatchedPolicies holds the identifiers of the policies (or Casbin rules, or Zanzibar relation tuples)
+	// which contributed to the decision.
+	MatchedPolicies []string `json:"matched_policies,omitempty"`
+	// MatchedStatements holds the identifiers of the policy statements which contributed to the decision,
+	// for engines which have a notion of statements.
+	MatchedStatements []string `json:"matched_statements,omitempty"`
+
+	// Reason explains why the request was denied, it is empty for allowed requests.
+	Reason string `json:"reason,omitempty"`
+
+	Duration time.Duration `json:"duration"`
+}
+
+// Allowed reports whether the decision grants access.
+func (d *Decision) Allowed() bool {
+	return d != nil && d.Effect == EffectAllow
+}
+
+// MakeAllowDecision creates an allowing Decision for the given engine.
+func MakeAllowDecision(engineName string, matchedPolicies ...string) *Decision {
+	return &Decision{
+		Effect:          EffectAllow,
+		Engine:          engineName,
+		MatchedPolicies: matchedPolicies,
+	}
+}
+
+// MakeDenyDecision creates a denying Decision for the given engine.
+func MakeDenyDecision(engineName string, reason string, matchedPolicies ...string) *Decision {
+	return &Decision{
+		Effect:          EffectDeny,
+		Engine:          engineName,
+		Reason:          reason,
+		MatchedPolicies: matchedPolicies,
+	}
+}
@@ -19,8 +19,24 @@ type Authorizer interface {
 	FilterAuthorizedProjects(ctx context.Context, subjects Subjects) (Projects, error)
 
 	IsAuthorized(ctx context.Context, subjects Subject, action Action, resource Resource, project Project) (bool, error)
+
+	Decide(ctx context.Context, subject Subject, action Action, resource Resource, project Project) (*Decision, error)
+
+	// BatchIsAuthorized checks several requests at once, the result has the same length and order as requests.
+	BatchIsAuthorized(ctx context.Context, requests Requests) ([]bool, error)
 }
 
 type Writer interface {
 	SetPolicies(ctx context.Context, policies PolicyMap, roles RoleMap) error
+
+	// AddPolicies adds the policies to the ones already set, their statement roles are resolved
+	// against the roles of the last SetPolicies call.
+	AddPolicies(ctx context.Context, policies Policies) error
+
+	// RemovePolicies removes the policies, previously set or added, with the same content.
+	RemovePolicies(ctx context.Context, policies Policies) error
+
+	AddRoleBindings(ctx context.Context, bindings RoleBindings) error
+
+	RemoveRoleBindings(ctx context.Context, bindings RoleBindings) error
 }
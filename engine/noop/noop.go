@@ -40,6 +40,34 @@ func (s State) IsAuthorized(_ context.Context, _ engine.Subject, _ engine.Action
 	return true, nil
 }
 
+func (s State) Decide(_ context.Context, _ engine.Subject, _ engine.Action, _ engine.Resource, _ engine.Project) (*engine.Decision, error) {
+	return engine.MakeAllowDecision(s.Name()), nil
+}
+
+func (s State) BatchIsAuthorized(_ context.Context, requests engine.Requests) ([]bool, error) {
+	result := make([]bool, len(requests))
+	for i := range result {
+		result[i] = true
+	}
+	return result, nil
+}
+
 func (s State) SetPolicies(_ context.Context, _ engine.PolicyMap, _ engine.RoleMap) error {
 	return nil
 }
+
+func (s State) AddPolicies(_ context.Context, _ engine.Policies) error {
+	return nil
+}
+
+func (s State) RemovePolicies(_ context.Context, _ engine.Policies) error {
+	return nil
+}
+
+func (s State) AddRoleBindings(_ context.Context, _ engine.RoleBindings) error {
+	return nil
+}
+
+func (s State) RemoveRoleBindings(_ context.Context, _ engine.RoleBindings) error {
+	return nil
+}
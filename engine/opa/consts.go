@@ -4,10 +4,14 @@ const (
 	AuthzProjectsQueryKey    = "AuthzProjectsQuery"
 	FilteredPairsQueryKey    = "FilteredPairsQuery"
 	FilteredProjectsQueryKey = "FilteredProjectsQuery"
+	DecisionQueryKey         = "DecisionQuery"
+	BatchQueryKey            = "BatchQuery"
 )
 
 const (
 	defaultAuthzProjectsQuery    = "data.authz.authorized_project[project]"
 	defaultFilteredPairsQuery    = "data.authz.introspection.authorized_pair[_]"
 	defaultFilteredProjectsQuery = "data.authz.introspection.authorized_project"
+	defaultDecisionQuery         = "data.authz.match = match; data.authz.authorized_project = projects; data.authz.authorized = authorized"
+	defaultBatchQuery            = "results = [[authorized, projects] | item = input.requests[_]; authorized = data.authz.authorized with input as item; projects = data.authz.authorized_project with input as item]"
 )
@@ -0,0 +1,353 @@
+package opa_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tx7do/kratos-authz/engine"
+	"github.com/tx7do/kratos-authz/engine/opa"
+)
+
+// These tests exercise the functions in opa.go through the engine interface.
+
+func testPoliciesAndRoles() (engine.PolicyMap, engine.RoleMap) {
+	policies := engine.PolicyMap{
+		"pol-admins": map[string]interface{}{
+			"members": []interface{}{"team:local:admins"},
+			"statements": map[string]interface{}{
+				"st-allow-teams": map[string]interface{}{
+					"effect":    "allow",
+					"resources": []interface{}{"iam:teams"},
+					"actions":   []interface{}{"iam:teams:*"},
+					"projects":  []interface{}{"~~ALL-PROJECTS~~"},
+				},
+				"st-allow-nodes": map[string]interface{}{
+					"effect":    "allow",
+					"resources": []interface{}{"infra:nodes:*"},
+					"role":      "viewer",
+					"projects":  []interface{}{"project1"},
+				},
+			},
+		},
+		"pol-deny": map[string]interface{}{
+			"members": []interface{}{"user:local:alice"},
+			"statements": map[string]interface{}{
+				"st-deny-teams": map[string]interface{}{
+					"effect":    "deny",
+					"resources": []interface{}{"iam:teams"},
+					"actions":   []interface{}{"iam:teams:delete"},
+					"projects":  []interface{}{"~~ALL-PROJECTS~~"},
+				},
+			},
+		},
+	}
+	roles := engine.RoleMap{
+		"viewer": map[string]interface{}{
+			"actions": []interface{}{"infra:nodes:get", "infra:nodes:list"},
+		},
+	}
+	return policies, roles
+}
+
+func newTestEngine(t *testing.T) *opa.State {
+	s, err := opa.NewEngine(t.Context())
+	require.NoError(t, err)
+
+	policies, roles := testPoliciesAndRoles()
+	require.NoError(t, s.SetPolicies(t.Context(), policies, roles))
+
+	return s
+}
+
+func TestDecide(t *testing.T) {
+	s := newTestEngine(t)
+
+	cases := map[string]struct {
+		subject    engine.Subject
+		action     engine.Action
+		resource   engine.Resource
+		project    engine.Project
+		effect     engine.Effect
+		policies   []string
+		statements []string
+	}{
+		"allowed without project": {
+			subject:    "team:local:admins",
+			action:     "iam:teams:create",
+			resource:   "iam:teams",
+			effect:     engine.EffectAllow,
+			policies:   []string{"pol-admins"},
+			statements: []string{"st-allow-teams"},
+		},
+		"allowed by role in project": {
+			subject:    "team:local:admins",
+			action:     "infra:nodes:get",
+			resource:   "infra:nodes:n1",
+			project:    "project1",
+			effect:     engine.EffectAllow,
+			policies:   []string{"pol-admins"},
+			statements: []string{"st-allow-nodes"},
+		},
+		"denied by project": {
+			subject:    "team:local:admins",
+			action:     "infra:nodes:get",
+			resource:   "infra:nodes:n1",
+			project:    "project2",
+			effect:     engine.EffectDeny,
+			policies:   []string{"pol-admins"},
+			statements: []string{"st-allow-nodes"},
+		},
+		"denied by statement": {
+			subject:    "user:local:alice",
+			action:     "iam:teams:delete",
+			resource:   "iam:teams",
+			effect:     engine.EffectDeny,
+			policies:   []string{"pol-deny"},
+			statements: []string{"st-deny-teams"},
+		},
+		"no match": {
+			subject:  "user:local:bob",
+			action:   "iam:teams:delete",
+			resource: "iam:teams",
+			effect:   engine.EffectDeny,
+		},
+	}
+
+	for descr, tc := range cases {
+		t.Run(descr, func(t *testing.T) {
+			d, err := s.Decide(t.Context(), tc.subject, tc.action, tc.resource, tc.project)
+			require.NoError(t, err)
+			assert.Equal(t, tc.effect, d.Effect)
+			assert.Equal(t, string(engine.Opa), d.Engine)
+			assert.Equal(t, tc.policies, d.MatchedPolicies)
+			assert.Equal(t, tc.statements, d.MatchedStatements)
+			if tc.effect == engine.EffectDeny {
+				assert.NotEmpty(t, d.Reason)
+			}
+
+			allowed, err := s.IsAuthorized(t.Context(), tc.subject, tc.action, tc.resource, tc.project)
+			require.NoError(t, err)
+			assert.Equal(t, d.Allowed(), allowed)
+		})
+	}
+}
+
+func TestBatchIsAuthorized(t *testing.T) {
+	s := newTestEngine(t)
+
+	requests := engine.MakeRequests(
+		engine.MakeRequest("team:local:admins", "iam:teams:create", "iam:teams", ""),
+		engine.MakeRequest("team:local:admins", "infra:nodes:get", "infra:nodes:n1", "project1"),
+		engine.MakeRequest("team:local:admins", "infra:nodes:get", "infra:nodes:n1", "project2"),
+		engine.MakeRequest("user:local:alice", "iam:teams:delete", "iam:teams", ""),
+		engine.MakeRequest("team:local:admins", "iam:teams:create", "iam:teams", "project3"),
+	)
+
+	r, err := s.BatchIsAuthorized(t.Context(), requests)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true, false, false, true}, r)
+
+	for i, req := range requests {
+		allowed, err := s.IsAuthorized(t.Context(), req.Subject, req.Action, req.Resource, req.Project)
+		require.NoError(t, err)
+		assert.Equalf(t, allowed, r[i], "request %d", i)
+	}
+
+	r, err = s.BatchIsAuthorized(t.Context(), engine.Requests{})
+	require.NoError(t, err)
+	assert.Empty(t, r)
+}
+
+func TestNoPolicies(t *testing.T) {
+	s, err := opa.NewEngine(t.Context())
+	require.NoError(t, err)
+
+	decision, err := s.Decide(t.Context(), "team:local:admins", "iam:teams:create", "iam:teams", "project1")
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed())
+
+	r, err := s.BatchIsAuthorized(t.Context(), engine.MakeRequests(
+		engine.MakeRequest("team:local:admins", "iam:teams:create", "iam:teams", ""),
+	))
+	require.NoError(t, err)
+	assert.Equal(t, []bool{false}, r)
+
+	projects, err := s.ProjectsAuthorized(t.Context(), engine.MakeSubjects("team:local:admins"), "iam:teams:create", "iam:teams", engine.MakeProjects("project1"))
+	require.NoError(t, err)
+	assert.Empty(t, projects)
+}
+
+func TestTypedPolicies(t *testing.T) {
+	s, err := opa.NewEngine(t.Context())
+	require.NoError(t, err)
+
+	policies := engine.MakePolicyMap(
+		engine.Policy{
+			ID:      "pol-admins",
+			Members: engine.MakeSubjects("team:local:admins"),
+			Statements: engine.Statements{
+				{
+					ID:        "st-teams",
+					Effect:    engine.EffectAllow,
+					Resources: engine.MakeResources("iam:teams"),
+					Actions:   engine.MakeActions("iam:teams:*"),
+				},
+				{
+					ID:        "st-nodes",
+					Effect:    engine.EffectAllow,
+					Resources: engine.MakeResources("infra:nodes:*"),
+					Role:      "viewer",
+					Projects:  engine.MakeProjects("project1", "project2"),
+				},
+			},
+		},
+		engine.Policy{
+			ID:      "pol-deny",
+			Members: engine.MakeSubjects("user:local:alice"),
+			Statements: engine.Statements{
+				{
+					Effect:    engine.EffectDeny,
+					Resources: engine.MakeResources("iam:teams"),
+					Actions:   engine.MakeActions("iam:teams:delete"),
+				},
+			},
+		},
+	)
+	roles := engine.MakeRoleMap(
+		engine.Roles{{ID: "viewer", Actions: engine.MakeActions("infra:nodes:get")}},
+		engine.RoleBindings{
+			{Subject: "user:local:alice", Role: "team:local:admins"},
+			{Subject: "user:local:bob", Role: "team:local:admins", Project: "project2"},
+		},
+	)
+	require.NoError(t, s.SetPolicies(t.Context(), policies, roles))
+
+	cases := map[string]struct {
+		subject  engine.Subject
+		action   engine.Action
+		resource engine.Resource
+		project  engine.Project
+		allowed  bool
+	}{
+		"member":                        {"team:local:admins", "iam:teams:create", "iam:teams", "", true},
+		"member in all projects":        {"team:local:admins", "iam:teams:create", "iam:teams", "project9", true},
+		"role action in project":        {"team:local:admins", "infra:nodes:get", "infra:nodes:n1", "project1", true},
+		"role action outside project":   {"team:local:admins", "infra:nodes:get", "infra:nodes:n1", "project3", false},
+		"bound subject":                 {"user:local:alice", "iam:teams:create", "iam:teams", "", true},
+		"bound subject denied":          {"user:local:alice", "iam:teams:delete", "iam:teams", "", false},
+		"project bound subject":         {"user:local:bob", "infra:nodes:get", "infra:nodes:n1", "project2", true},
+		"project bound subject outside": {"user:local:bob", "infra:nodes:get", "infra:nodes:n1", "project1", false},
+		"project bound wildcard":        {"user:local:bob", "iam:teams:create", "iam:teams", "project2", true},
+		"unknown subject":               {"user:local:eve", "iam:teams:create", "iam:teams", "", false},
+	}
+
+	for descr, tc := range cases {
+		t.Run(descr, func(t *testing.T) {
+			allowed, err := s.IsAuthorized(t.Context(), tc.subject, tc.action, tc.resource, tc.project)
+			require.NoError(t, err)
+			assert.Equal(t, tc.allowed, allowed)
+		})
+	}
+}
+
+func TestIncrementalPolicies(t *testing.T) {
+	s, err := opa.NewEngine(t.Context())
+	require.NoError(t, err)
+
+	admins := engine.Policy{
+		ID:      "pol-admins",
+		Members: engine.MakeSubjects("team:local:admins"),
+		Statements: engine.Statements{
+			{
+				Effect:    engine.EffectAllow,
+				Resources: engine.MakeResources("iam:teams"),
+				Actions:   engine.MakeActions("iam:teams:create"),
+				Projects:  engine.MakeProjects("project1", "project2"),
+			},
+		},
+	}
+
+	isAuthorized := func(subject engine.Subject, project engine.Project) bool {
+		allowed, err := s.IsAuthorized(t.Context(), subject, "iam:teams:create", "iam:teams", project)
+		require.NoError(t, err)
+		return allowed
+	}
+	projectsAuthorized := func(subject engine.Subject) engine.Projects {
+		projects, err := s.ProjectsAuthorized(t.Context(), engine.MakeSubjects(subject), "iam:teams:create", "iam:teams", engine.MakeProjects("project1", "project2"))
+		require.NoError(t, err)
+		return projects
+	}
+
+	require.ErrorIs(t, s.AddPolicies(t.Context(), engine.Policies{{Members: admins.Members}}), opa.ErrMissingPolicyID)
+
+	// works before SetPolicies has been called
+	require.NoError(t, s.AddPolicies(t.Context(), engine.Policies{admins}))
+	assert.True(t, isAuthorized("team:local:admins", "project1"))
+	assert.False(t, isAuthorized("user:local:bob", "project1"))
+
+	require.NoError(t, s.AddRoleBindings(t.Context(), engine.RoleBindings{
+		{Subject: "user:local:bob", Role: "team:local:admins", Project: "project2"},
+	}))
+	assert.False(t, isAuthorized("user:local:bob", "project1"))
+	assert.True(t, isAuthorized("user:local:bob", "project2"))
+	assert.ElementsMatch(t, engine.MakeProjects("project2"), projectsAuthorized("user:local:bob"))
+
+	require.NoError(t, s.RemoveRoleBindings(t.Context(), engine.RoleBindings{
+		{Subject: "user:local:bob", Role: "team:local:admins", Project: "project2"},
+	}))
+	assert.False(t, isAuthorized("user:local:bob", "project2"))
+	assert.Empty(t, projectsAuthorized("user:local:bob"))
+
+	// a policy is only removed with the same content
+	changed := admins
+	changed.Members = engine.MakeSubjects("team:local:others")
+	require.NoError(t, s.RemovePolicies(t.Context(), engine.Policies{changed}))
+	assert.True(t, isAuthorized("team:local:admins", "project1"))
+
+	require.NoError(t, s.RemovePolicies(t.Context(), engine.Policies{admins}))
+	assert.False(t, isAuthorized("team:local:admins", "project1"))
+	assert.Empty(t, projectsAuthorized("team:local:admins"))
+}
+
+// TestConcurrentWrites checks while the policies are written, it is meant to be run with -race.
+func TestConcurrentWrites(t *testing.T) {
+	s := newTestEngine(t)
+	policies, roles := testPoliciesAndRoles()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			assert.NoError(t, s.SetPolicies(t.Context(), policies, roles))
+			assert.NoError(t, s.AddPolicies(t.Context(), engine.Policies{{
+				ID:         "pol-bob",
+				Members:    engine.MakeSubjects("user:local:bob"),
+				Statements: engine.Statements{{Effect: engine.EffectAllow, Resources: engine.MakeResources("iam:teams"), Actions: engine.MakeActions("iam:teams:create")}},
+			}}))
+		}
+	}()
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				allowed, err := s.IsAuthorized(t.Context(), "team:local:admins", "iam:teams:create", "iam:teams", "project1")
+				assert.NoError(t, err)
+				assert.True(t, allowed)
+
+				_, err = s.BatchIsAuthorized(t.Context(), engine.MakeRequests(engine.MakeRequest("user:local:bob", "iam:teams:create", "iam:teams", "")))
+				assert.NoError(t, err)
+				_, err = s.ProjectsAuthorized(t.Context(), engine.MakeSubjects("team:local:admins"), "iam:teams:create", "iam:teams", engine.MakeProjects("project1"))
+				assert.NoError(t, err)
+				_, err = s.FilterAuthorizedPairs(t.Context(), engine.MakeSubjects("team:local:admins"), engine.Pairs{engine.MakePair("iam:teams", "iam:teams:create")})
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
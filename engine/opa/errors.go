@@ -1,10 +1,15 @@
 package opa
 
 import (
+	"errors"
 	"fmt"
+
 	"github.com/open-policy-agent/opa/rego"
 )
 
+// ErrMissingPolicyID is returned by the incremental writes for policies without an ID.
+var ErrMissingPolicyID = errors.New("policy ID is required for incremental updates")
+
 type UnexpectedResultExpressionError struct {
 	exps []*rego.ExpressionValue
 }
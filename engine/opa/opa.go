@@ -9,7 +9,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-kratos/kratos/v2/log"
 	"github.com/pkg/errors"
@@ -41,11 +44,26 @@ func init() {
 var _ engine.Engine = (*State)(nil)
 
 type State struct {
+	// dataMu guards the store and the queries prepared against it, which SetPolicies replaces
+	// while the checks are running. The checks evaluate the queries they have read, outside the lock.
+	dataMu               sync.RWMutex
 	store                storage.Store
 	queries              map[string]ast.Body
 	compiler             *ast.Compiler
 	modules              map[string]*ast.Module
 	preparedEvalProjects rego.PreparedEvalQuery
+	preparedEvalDecision rego.PreparedEvalQuery
+	preparedEvalBatch    rego.PreparedEvalQuery
+
+	// the projects query is partially evaluated against the store data, it is rebuilt lazily
+	// once the store has been patched by an incremental write.
+	projectsMu         sync.Mutex
+	projectsQueryStale bool
+
+	// the engine-neutral policies and role bindings, kept for the incremental writes
+	writeMu  sync.Mutex
+	policies map[string]engine.Policy
+	bindings engine.RoleBindings
 
 	regoVersion       ast.RegoVersion
 	enableQueryTracer bool
@@ -57,10 +75,11 @@ type State struct {
 	log *log.Helper
 }
 
-func NewEngine(_ context.Context, opts ...OptFunc) (*State, error) {
+func NewEngine(ctx context.Context, opts ...OptFunc) (*State, error) {
 	s := State{
 		store:                 inmem.New(),
 		queries:               make(map[string]ast.Body),
+		policies:              make(map[string]engine.Policy),
 		log:                   log.NewHelper(log.With(log.DefaultLogger, "module", "opa.authz.engine")),
 		regoVersion:           ast.DefaultRegoVersion,
 		enableQueryTracer:     false,
@@ -73,6 +92,11 @@ func NewEngine(_ context.Context, opts ...OptFunc) (*State, error) {
 		return nil, err
 	}
 
+	// the queries are prepared against the empty store, so an engine without policies denies every request
+	if err := s.prepareQueries(ctx, s.store); err != nil {
+		return nil, errors.Wrap(err, "prepare queries")
+	}
+
 	return &s, nil
 }
 
@@ -187,7 +211,12 @@ func (s *State) ProjectsAuthorized(
 		[2]*ast.Term{ast.NewTerm(ast.String("action")), ast.NewTerm(ast.String(action))},
 		[2]*ast.Term{ast.NewTerm(ast.String("projects")), ast.ArrayTerm(projs...)},
 	)
-	resultSet, err := s.preparedEvalProjects.Eval(ctx, rego.EvalParsedInput(input))
+	query, err := s.projectsQuery(ctx)
+	if err != nil {
+		return engine.Projects{}, err
+	}
+
+	resultSet, err := query.Eval(ctx, rego.EvalParsedInput(input))
 	if err != nil {
 		s.log.Errorf("failed to evaluate projects query: %v", err)
 		return engine.Projects{}, &EvaluationError{e: err}
@@ -206,7 +235,7 @@ func (s *State) FilterAuthorizedPairs(
 		"pairs":    pairs,
 	}
 
-	rs, err := s.evalQuery(ctx, s.queries[FilteredPairsQueryKey], opaInput, s.store)
+	rs, err := s.evalQuery(ctx, s.queries[FilteredPairsQueryKey], opaInput, s.currentStore())
 	if err != nil {
 		s.log.Errorf("failed to evaluate filtered pairs query: %v", err)
 		return nil, &EvaluationError{e: err}
@@ -220,7 +249,7 @@ func (s *State) FilterAuthorizedProjects(ctx context.Context, subjects engine.Su
 		"subjects": subjects,
 	}
 
-	rs, err := s.evalQuery(ctx, s.queries[FilteredProjectsQueryKey], opaInput, s.store)
+	rs, err := s.evalQuery(ctx, s.queries[FilteredProjectsQueryKey], opaInput, s.currentStore())
 	if err != nil {
 		s.log.Errorf("failed to evaluate filtered projects query: %v", err)
 		return nil, &EvaluationError{e: err}
@@ -236,42 +265,165 @@ func (s *State) IsAuthorized(
 	resource engine.Resource,
 	project engine.Project,
 ) (bool, error) {
+	decision, err := s.Decide(ctx, subject, action, resource, project)
+	if err != nil {
+		return false, err
+	}
+	return decision.Allowed(), nil
+}
+
+func (s *State) Decide(
+	ctx context.Context,
+	subject engine.Subject,
+	action engine.Action,
+	resource engine.Resource,
+	project engine.Project,
+) (*engine.Decision, error) {
+	start := time.Now()
+
+	var projs []*ast.Term
 	if len(project) > 0 {
-		input := ast.NewObject(
-			[2]*ast.Term{ast.NewTerm(ast.String("subjects")), ast.ArrayTerm(ast.NewTerm(ast.String(subject)))},
-			[2]*ast.Term{ast.NewTerm(ast.String("resource")), ast.NewTerm(ast.String(resource))},
-			[2]*ast.Term{ast.NewTerm(ast.String("action")), ast.NewTerm(ast.String(action))},
-			[2]*ast.Term{ast.NewTerm(ast.String("projects")), ast.ArrayTerm(ast.NewTerm(ast.String(project)))},
-		)
-		resultSet, err := s.preparedEvalProjects.Eval(ctx, rego.EvalParsedInput(input))
-		if err != nil {
-			s.log.Errorf("failed to evaluate projects query: %v", err)
-			return false, &EvaluationError{e: err}
-		}
-		return s.allowedFromPreparedEvalQuery(resultSet)
-	} else {
-		opaInput := map[string]interface{}{
-			"subjects": engine.MakeSubjects(subject),
-			"pairs":    engine.MakePairs(engine.Pair{Resource: resource, Action: action}),
-		}
+		projs = append(projs, ast.NewTerm(ast.String(project)))
+	}
 
-		rs, err := s.evalQuery(ctx, s.queries[FilteredPairsQueryKey], opaInput, s.store)
-		if err != nil {
-			s.log.Errorf("failed to evaluate filtered pairs query: %v", err)
-			return false, &EvaluationError{e: err}
+	input := ast.NewObject(
+		[2]*ast.Term{ast.NewTerm(ast.String("subjects")), ast.ArrayTerm(ast.NewTerm(ast.String(subject)))},
+		[2]*ast.Term{ast.NewTerm(ast.String("resource")), ast.NewTerm(ast.String(resource))},
+		[2]*ast.Term{ast.NewTerm(ast.String("action")), ast.NewTerm(ast.String(action))},
+		[2]*ast.Term{ast.NewTerm(ast.String("projects")), ast.ArrayTerm(projs...)},
+	)
+	s.dataMu.RLock()
+	query := s.preparedEvalDecision
+	s.dataMu.RUnlock()
+
+	resultSet, err := query.Eval(ctx, rego.EvalParsedInput(input))
+	if err != nil {
+		s.log.Errorf("failed to evaluate decision query: %v", err)
+		return nil, &EvaluationError{e: err}
+	}
+
+	decision, err := s.decisionFromPreparedEvalQuery(resultSet, project)
+	if err != nil {
+		return nil, err
+	}
+	decision.Duration = time.Since(start)
+
+	return decision, nil
+}
+
+func (s *State) BatchIsAuthorized(ctx context.Context, requests engine.Requests) ([]bool, error) {
+	if len(requests) == 0 {
+		return []bool{}, nil
+	}
+
+	items := make([]*ast.Term, 0, len(requests))
+	for _, r := range requests {
+		var projs []*ast.Term
+		if len(r.Project) > 0 {
+			projs = append(projs, ast.NewTerm(ast.String(r.Project)))
 		}
+		items = append(items, ast.ObjectTerm(
+			[2]*ast.Term{ast.NewTerm(ast.String("subjects")), ast.ArrayTerm(ast.NewTerm(ast.String(r.Subject)))},
+			[2]*ast.Term{ast.NewTerm(ast.String("resource")), ast.NewTerm(ast.String(r.Resource))},
+			[2]*ast.Term{ast.NewTerm(ast.String("action")), ast.NewTerm(ast.String(r.Action))},
+			[2]*ast.Term{ast.NewTerm(ast.String("projects")), ast.ArrayTerm(projs...)},
+		))
+	}
+
+	input := ast.NewObject(
+		[2]*ast.Term{ast.NewTerm(ast.String("requests")), ast.ArrayTerm(items...)},
+	)
+	s.dataMu.RLock()
+	query := s.preparedEvalBatch
+	s.dataMu.RUnlock()
 
-		return s.pairsFromAllowed(rs)
+	resultSet, err := query.Eval(ctx, rego.EvalParsedInput(input))
+	if err != nil {
+		s.log.Errorf("failed to evaluate batch query: %v", err)
+		return nil, &EvaluationError{e: err}
 	}
+
+	return s.batchFromPreparedEvalQuery(resultSet, requests)
 }
 
 func (s *State) SetPolicies(ctx context.Context, policyMap engine.PolicyMap, roleMap engine.RoleMap) error {
-	s.store = inmem.NewFromObject(map[string]interface{}{
-		"policies": policyMap,
-		"roles":    roleMap,
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	policies, roles := storeData(policyMap, roleMap)
+	store := inmem.NewFromObject(map[string]interface{}{
+		"policies": policies,
+		"roles":    roles,
 	})
 
-	return s.makeAuthorizedProjectPreparedQuery(ctx)
+	if err := s.prepareQueries(ctx, store); err != nil {
+		return err
+	}
+
+	typedPolicies, _ := policyMap.Policies()
+	s.policies = make(map[string]engine.Policy, len(typedPolicies))
+	for i, policy := range typedPolicies {
+		s.policies[policyID(policy, i)] = policy
+	}
+	s.bindings, _ = roleMap.RoleBindings()
+
+	return nil
+}
+
+// prepareQueries replaces the store, and prepares the queries against it. The previous store and
+// queries are kept when one of the queries cannot be prepared.
+func (s *State) prepareQueries(ctx context.Context, store storage.Store) error {
+	s.dataMu.Lock()
+	defer s.dataMu.Unlock()
+	s.projectsMu.Lock()
+	defer s.projectsMu.Unlock()
+
+	previousStore := s.store
+	previousProjects, previousDecision, previousBatch := s.preparedEvalProjects, s.preparedEvalDecision, s.preparedEvalBatch
+	previousStale := s.projectsQueryStale
+
+	s.store = store
+	err := s.makeAuthorizedProjectPreparedQuery(ctx)
+	if err == nil {
+		err = s.makeDecisionPreparedQuery(ctx)
+	}
+	if err == nil {
+		err = s.makeBatchPreparedQuery(ctx)
+	}
+	if err != nil {
+		s.store = previousStore
+		s.preparedEvalProjects, s.preparedEvalDecision, s.preparedEvalBatch = previousProjects, previousDecision, previousBatch
+		s.projectsQueryStale = previousStale
+		return err
+	}
+	s.projectsQueryStale = false
+
+	return nil
+}
+
+// currentStore returns the store the checks are evaluated against.
+func (s *State) currentStore() storage.Store {
+	s.dataMu.RLock()
+	defer s.dataMu.RUnlock()
+
+	return s.store
+}
+
+// projectsQuery returns the prepared projects query, rebuilt when the store has been patched since.
+func (s *State) projectsQuery(ctx context.Context) (rego.PreparedEvalQuery, error) {
+	s.dataMu.RLock()
+	defer s.dataMu.RUnlock()
+	s.projectsMu.Lock()
+	defer s.projectsMu.Unlock()
+
+	if s.projectsQueryStale {
+		if err := s.makeAuthorizedProjectPreparedQuery(ctx); err != nil {
+			return rego.PreparedEvalQuery{}, err
+		}
+		s.projectsQueryStale = false
+	}
+
+	return s.preparedEvalProjects, nil
 }
 
 func (s *State) InitModulesFromFiles(modules map[string]string) error {
@@ -356,6 +508,12 @@ func (s *State) initQueries() error {
 	if err = s.ParseFilterProjectsQuery(s.filteredProjectsQuery); err != nil {
 		return errors.Wrap(err, "parse filter projects query")
 	}
+	if s.queries[DecisionQueryKey], err = ast.ParseBody(defaultDecisionQuery); err != nil {
+		return errors.Wrap(err, "parse decision query")
+	}
+	if s.queries[BatchQueryKey], err = ast.ParseBody(defaultBatchQuery); err != nil {
+		return errors.Wrap(err, "parse batch query")
+	}
 
 	return nil
 }
@@ -441,6 +599,44 @@ func (s *State) makeAuthorizedProjectPreparedQuery(ctx context.Context) error {
 	return nil
 }
 
+func (s *State) makeDecisionPreparedQuery(ctx context.Context) error {
+	r := rego.New(
+		rego.Store(s.store),
+		rego.Compiler(s.compiler),
+		rego.ParsedQuery(s.queries[DecisionQueryKey]),
+		rego.SetRegoVersion(s.regoVersion),
+	)
+
+	query, err := r.PrepareForEval(ctx)
+	if err != nil {
+		s.log.Errorf("failed to prepare for eval: %v", err)
+		return errors.Wrap(err, "prepare query for eval (decision)")
+	}
+
+	s.preparedEvalDecision = query
+
+	return nil
+}
+
+func (s *State) makeBatchPreparedQuery(ctx context.Context) error {
+	r := rego.New(
+		rego.Store(s.store),
+		rego.Compiler(s.compiler),
+		rego.ParsedQuery(s.queries[BatchQueryKey]),
+		rego.SetRegoVersion(s.regoVersion),
+	)
+
+	query, err := r.PrepareForEval(ctx)
+	if err != nil {
+		s.log.Errorf("failed to prepare for eval: %v", err)
+		return errors.Wrap(err, "prepare query for eval (batch)")
+	}
+
+	s.preparedEvalBatch = query
+
+	return nil
+}
+
 func (s *State) newCompiler() (*ast.Compiler, error) {
 	compiler := ast.NewCompiler()
 	compiler.Compile(s.modules)
@@ -453,7 +649,7 @@ func (s *State) newCompiler() (*ast.Compiler, error) {
 }
 
 func (s *State) DumpData(ctx context.Context) error {
-	return s.dumpData(ctx, s.store)
+	return s.dumpData(ctx, s.currentStore())
 }
 
 func (s *State) dumpData(ctx context.Context, store storage.Store) error {
@@ -528,29 +724,6 @@ func (s *State) pairsFromResults(rs rego.ResultSet) (engine.Pairs, error) {
 	return pairs, nil
 }
 
-func (s *State) pairsFromAllowed(rs rego.ResultSet) (bool, error) {
-	for _, r := range rs {
-		if len(r.Expressions) != 1 {
-			return false, &UnexpectedResultExpressionError{exps: r.Expressions}
-		}
-		m, ok := r.Expressions[0].Value.(map[string]interface{})
-		if !ok {
-			return false, &UnexpectedResultExpressionError{exps: r.Expressions}
-		}
-		_, ok = m["resource"].(string)
-		if !ok {
-			return false, &UnexpectedResultExpressionError{exps: r.Expressions}
-		}
-		_, ok = m["action"].(string)
-		if !ok {
-			return false, &UnexpectedResultExpressionError{exps: r.Expressions}
-		}
-		return true, nil
-	}
-
-	return false, nil
-}
-
 func (s *State) projectsFromPartialResults(rs rego.ResultSet) (engine.Projects, error) {
 	if len(rs) != 1 {
 		return nil, &UnexpectedResultSetError{set: rs}
@@ -606,14 +779,123 @@ func (s *State) projectsFromPreparedEvalQuery(rs rego.ResultSet) (engine.Project
 	return result, nil
 }
 
-func (s *State) allowedFromPreparedEvalQuery(rs rego.ResultSet) (bool, error) {
-	var ok bool
-	for i := range rs {
-		_, ok = rs[i].Bindings["project"].(string)
+func (s *State) decisionFromPreparedEvalQuery(rs rego.ResultSet, project engine.Project) (*engine.Decision, error) {
+	if len(rs) != 1 {
+		return nil, &UnexpectedResultSetError{set: rs}
+	}
+
+	var allowed bool
+	if len(project) > 0 {
+		projects, ok := rs[0].Bindings["projects"].([]interface{})
 		if !ok {
-			return false, &UnexpectedResultExpressionError{exps: rs[i].Expressions}
+			return nil, &UnexpectedResultExpressionError{exps: rs[0].Expressions}
 		}
-		return true, nil
+		for _, p := range projects {
+			if p == string(project) {
+				allowed = true
+				break
+			}
+		}
+	} else {
+		var ok bool
+		if allowed, ok = rs[0].Bindings["authorized"].(bool); !ok {
+			return nil, &UnexpectedResultExpressionError{exps: rs[0].Expressions}
+		}
+	}
+
+	matches, ok := rs[0].Bindings["match"].([]interface{})
+	if !ok {
+		return nil, &UnexpectedResultExpressionError{exps: rs[0].Expressions}
+	}
+
+	matchedPolicies := map[engine.Effect]map[string]bool{}
+	matchedStatements := map[engine.Effect]map[string]bool{}
+	for _, m := range matches {
+		tuple, ok := m.([]interface{})
+		if !ok || len(tuple) != 3 {
+			return nil, &UnexpectedResultExpressionError{exps: rs[0].Expressions}
+		}
+		effect, _ := tuple[0].(string)
+		polID, _ := tuple[1].(string)
+		statementID, _ := tuple[2].(string)
+
+		eff := engine.Effect(effect)
+		if matchedPolicies[eff] == nil {
+			matchedPolicies[eff] = map[string]bool{}
+			matchedStatements[eff] = map[string]bool{}
+		}
+		matchedPolicies[eff][polID] = true
+		matchedStatements[eff][statementID] = true
+	}
+
+	var decision *engine.Decision
+	switch {
+	case allowed:
+		decision = engine.MakeAllowDecision(s.Name(), sortedKeys(matchedPolicies[engine.EffectAllow])...)
+		decision.MatchedStatements = sortedKeys(matchedStatements[engine.EffectAllow])
+
+	case len(matchedPolicies[engine.EffectDeny]) > 0:
+		decision = engine.MakeDenyDecision(s.Name(), "denied by policy statement", sortedKeys(matchedPolicies[engine.EffectDeny])...)
+		decision.MatchedStatements = sortedKeys(matchedStatements[engine.EffectDeny])
+
+	case len(matchedPolicies[engine.EffectAllow]) > 0:
+		decision = engine.MakeDenyDecision(s.Name(), fmt.Sprintf("no matching policy statement for project %q", project), sortedKeys(matchedPolicies[engine.EffectAllow])...)
+		decision.MatchedStatements = sortedKeys(matchedStatements[engine.EffectAllow])
+
+	default:
+		decision = engine.MakeDenyDecision(s.Name(), "no matching policy statement")
+	}
+
+	return decision, nil
+}
+
+func (s *State) batchFromPreparedEvalQuery(rs rego.ResultSet, requests engine.Requests) ([]bool, error) {
+	if len(rs) != 1 {
+		return nil, &UnexpectedResultSetError{set: rs}
+	}
+
+	results, ok := rs[0].Bindings["results"].([]interface{})
+	if !ok || len(results) != len(requests) {
+		return nil, &UnexpectedResultExpressionError{exps: rs[0].Expressions}
+	}
+
+	allowed := make([]bool, len(requests))
+	for i, r := range results {
+		pair, ok := r.([]interface{})
+		if !ok || len(pair) != 2 {
+			return nil, &UnexpectedResultExpressionError{exps: rs[0].Expressions}
+		}
+
+		if len(requests[i].Project) == 0 {
+			if allowed[i], ok = pair[0].(bool); !ok {
+				return nil, &UnexpectedResultExpressionError{exps: rs[0].Expressions}
+			}
+			continue
+		}
+
+		projects, ok := pair[1].([]interface{})
+		if !ok {
+			return nil, &UnexpectedResultExpressionError{exps: rs[0].Expressions}
+		}
+		for _, p := range projects {
+			if p == string(requests[i].Project) {
+				allowed[i] = true
+				break
+			}
+		}
+	}
+
+	return allowed, nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+	if len(m) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
 	}
-	return false, nil
+	sort.Strings(keys)
+	return keys
 }
@@ -0,0 +1,151 @@
+package opa
+
+import (
+	"strconv"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+// storeData converts the policy and role maps into the "policies" and "roles" documents
+// expected by the rego modules.
+// Engine-neutral policies are translated, any other entry is passed through unchanged.
+func storeData(policyMap engine.PolicyMap, roleMap engine.RoleMap) (map[string]interface{}, map[string]interface{}) {
+	policies, hasPolicies := policyMap.Policies()
+	roles, hasRoles := roleMap.Roles()
+	bindings, hasBindings := roleMap.RoleBindings()
+	if !hasPolicies && !hasRoles && !hasBindings {
+		return policyMap, roleMap
+	}
+
+	policiesData := make(map[string]interface{}, len(policyMap)+len(policies))
+	for k, v := range policyMap {
+		if k != engine.PoliciesKey {
+			policiesData[k] = v
+		}
+	}
+	rolesData := make(map[string]interface{}, len(roleMap)+len(roles))
+	for k, v := range roleMap {
+		if k != engine.RolesKey && k != engine.RoleBindingsKey {
+			rolesData[k] = v
+		}
+	}
+
+	for i, policy := range policies {
+		for k, v := range policyEntries(policyID(policy, i), policy, bindings) {
+			policiesData[k] = v
+		}
+	}
+
+	for _, role := range roles {
+		rolesData[role.ID] = map[string]interface{}{
+			"name":    role.Name,
+			"actions": stringList(role.Actions),
+		}
+	}
+
+	return policiesData, rolesData
+}
+
+// policyID returns the ID of the policy, or its index when it has none.
+func policyID(policy engine.Policy, index int) string {
+	if policy.ID != "" {
+		return policy.ID
+	}
+	return strconv.Itoa(index)
+}
+
+// policyEntries returns the "policies" documents of a policy: the policy itself, with the subjects
+// bound to it in every project added to its members, and a copy restricted to the project for every
+// project bound subject.
+func policyEntries(id string, policy engine.Policy, bindings engine.RoleBindings) map[string]interface{} {
+	entries := map[string]interface{}{}
+
+	members := stringList(policy.Members)
+
+	// role bindings without a project are memberships of the bound policies
+	for _, binding := range bindings {
+		if isAllProjects(binding.Project) && containsMember(policy.Members, binding.Role) {
+			members = append(members, string(binding.Subject))
+		}
+	}
+
+	entries[id] = policyData(policy, members, policy.Statements)
+
+	// project bound role bindings get a copy of the policy restricted to the project
+	for _, binding := range bindings {
+		if isAllProjects(binding.Project) || !containsMember(policy.Members, binding.Role) {
+			continue
+		}
+		statements := restrictStatements(policy.Statements, binding.Project)
+		if len(statements) == 0 {
+			continue
+		}
+		entries[id+"#"+string(binding.Subject)+"@"+string(binding.Project)] =
+			policyData(policy, []interface{}{string(binding.Subject)}, statements)
+	}
+
+	return entries
+}
+
+func policyData(policy engine.Policy, members []interface{}, statements engine.Statements) map[string]interface{} {
+	statementsData := make(map[string]interface{}, len(statements))
+	for i, statement := range statements {
+		id := statement.ID
+		if id == "" {
+			id = strconv.Itoa(i)
+		}
+		data := map[string]interface{}{
+			"effect":    string(statement.Effect),
+			"resources": stringList(statement.Resources),
+			"actions":   stringList(statement.Actions),
+			"projects":  stringList(statement.EffectiveProjects()),
+		}
+		if statement.Role != "" {
+			data["role"] = statement.Role
+		}
+		statementsData[id] = data
+	}
+
+	return map[string]interface{}{
+		"name":       policy.Name,
+		"type":       policy.Type,
+		"members":    members,
+		"statements": statementsData,
+	}
+}
+
+// restrictStatements returns the statements applying to project, limited to that project.
+func restrictStatements(statements engine.Statements, project engine.Project) engine.Statements {
+	var result engine.Statements
+	for _, statement := range statements {
+		for _, p := range statement.EffectiveProjects() {
+			if p == engine.AllProjects || p == project {
+				statement.Projects = engine.Projects{project}
+				result = append(result, statement)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func isAllProjects(project engine.Project) bool {
+	return project == "" || project == engine.AllProjects
+}
+
+func containsMember(members engine.Subjects, role string) bool {
+	for _, m := range members {
+		if string(m) == role {
+			return true
+		}
+	}
+	return false
+}
+
+func stringList[S ~[]T, T ~string](values S) []interface{} {
+	result := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		result = append(result, string(v))
+	}
+	return result
+}
@@ -0,0 +1,164 @@
+package opa
+
+import (
+	"context"
+	"reflect"
+	"slices"
+
+	"github.com/open-policy-agent/opa/storage"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+// AddPolicies writes the policies into the store, replacing the ones with the same ID.
+func (s *State) AddPolicies(ctx context.Context, policies engine.Policies) error {
+	ids, err := policyIDs(policies)
+	if err != nil {
+		return err
+	}
+
+	return s.patchPolicies(ctx, func() []string { return ids }, func() {
+		for _, policy := range policies {
+			s.policies[policy.ID] = policy
+		}
+	})
+}
+
+// RemovePolicies removes the policies with the same content from the store, the policies
+// whose ID has since been given another content are kept.
+func (s *State) RemovePolicies(ctx context.Context, policies engine.Policies) error {
+	ids, err := policyIDs(policies)
+	if err != nil {
+		return err
+	}
+
+	return s.patchPolicies(ctx, func() []string { return ids }, func() {
+		for _, policy := range policies {
+			if current, ok := s.policies[policy.ID]; ok && reflect.DeepEqual(current, policy) {
+				delete(s.policies, policy.ID)
+			}
+		}
+	})
+}
+
+// AddRoleBindings rewrites the policies of the bound roles.
+func (s *State) AddRoleBindings(ctx context.Context, bindings engine.RoleBindings) error {
+	return s.patchPolicies(ctx, func() []string { return s.boundPolicyIDs(bindings) }, func() {
+		for _, binding := range bindings {
+			if !slices.Contains(s.bindings, binding) {
+				s.bindings = append(s.bindings, binding)
+			}
+		}
+	})
+}
+
+// RemoveRoleBindings rewrites the policies of the unbound roles.
+func (s *State) RemoveRoleBindings(ctx context.Context, bindings engine.RoleBindings) error {
+	return s.patchPolicies(ctx, func() []string { return s.boundPolicyIDs(bindings) }, func() {
+		s.bindings = slices.DeleteFunc(s.bindings, func(binding engine.RoleBinding) bool {
+			return slices.Contains(bindings, binding)
+		})
+	})
+}
+
+// patchPolicies applies update to the engine-neutral policies, and patches the store documents
+// of the policies returned by affected accordingly.
+func (s *State) patchPolicies(ctx context.Context, affected func() []string, update func()) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	ids := affected()
+	before := s.policyEntries(ids)
+	update()
+	after := s.policyEntries(ids)
+
+	txn, err := s.store.NewTransaction(ctx, storage.WriteParams)
+	if err != nil {
+		s.log.Errorf("failed to open store transaction: %v", err)
+		return err
+	}
+
+	if err = s.writePolicyEntries(ctx, txn, before, after); err != nil {
+		s.log.Errorf("failed to patch policies: %v", err)
+		s.store.Abort(ctx, txn)
+		return err
+	}
+
+	if err = s.store.Commit(ctx, txn); err != nil {
+		s.log.Errorf("failed to commit store transaction: %v", err)
+		return err
+	}
+
+	s.projectsMu.Lock()
+	s.projectsQueryStale = true
+	s.projectsMu.Unlock()
+
+	return nil
+}
+
+func (s *State) writePolicyEntries(ctx context.Context, txn storage.Transaction, before, after map[string]interface{}) error {
+	if _, err := s.store.Read(ctx, txn, storage.Path{"policies"}); storage.IsNotFound(err) {
+		if err = s.store.Write(ctx, txn, storage.AddOp, storage.Path{"policies"}, map[string]interface{}{}); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	for id := range before {
+		if _, ok := after[id]; ok {
+			continue
+		}
+		if err := s.store.Write(ctx, txn, storage.RemoveOp, storage.Path{"policies", id}, nil); err != nil && !storage.IsNotFound(err) {
+			return err
+		}
+	}
+
+	for id, data := range after {
+		if err := s.store.Write(ctx, txn, storage.AddOp, storage.Path{"policies", id}, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// policyEntries returns the store documents of the given policies.
+func (s *State) policyEntries(ids []string) map[string]interface{} {
+	entries := map[string]interface{}{}
+	for _, id := range ids {
+		policy, ok := s.policies[id]
+		if !ok {
+			continue
+		}
+		for k, v := range policyEntries(id, policy, s.bindings) {
+			entries[k] = v
+		}
+	}
+	return entries
+}
+
+// boundPolicyIDs returns the IDs of the policies whose members include a role of the bindings.
+func (s *State) boundPolicyIDs(bindings engine.RoleBindings) []string {
+	var ids []string
+	for id, policy := range s.policies {
+		for _, binding := range bindings {
+			if containsMember(policy.Members, binding.Role) {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+func policyIDs(policies engine.Policies) ([]string, error) {
+	ids := make([]string, 0, len(policies))
+	for _, policy := range policies {
+		if policy.ID == "" {
+			return nil, ErrMissingPolicyID
+		}
+		ids = append(ids, policy.ID)
+	}
+	return ids, nil
+}
@@ -41,5 +41,20 @@ func MakePairs(pairs ...Pair) Pairs {
 	return pairs
 }
 
+type Request struct {
+	Subject  Subject  `json:"subject"`
+	Action   Action   `json:"action"`
+	Resource Resource `json:"resource"`
+	Project  Project  `json:"project,omitempty"`
+}
+type Requests []Request
+
+func MakeRequest(sub Subject, act Action, res Resource, proj Project) Request {
+	return Request{Subject: sub, Action: act, Resource: res, Project: proj}
+}
+func MakeRequests(requests ...Request) Requests {
+	return requests
+}
+
 type PolicyMap map[string]interface{}
 type RoleMap map[string]interface{}
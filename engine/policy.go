@@ -0,0 +1,123 @@
+package engine
+
+// AllProjects is the project which grants a statement in every project.
+const AllProjects Project = "~~ALL-PROJECTS~~"
+
+// Keys under which the engine-neutral policy model is carried in a PolicyMap / RoleMap.
+const (
+	PoliciesKey     = "~~POLICIES~~"
+	RolesKey        = "~~ROLES~~"
+	RoleBindingsKey = "~~ROLE-BINDINGS~~"
+)
+
+// Policy grants (or denies) its members the permissions described by its statements.
+type Policy struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name,omitempty"`
+	Type       string     `json:"type,omitempty"`
+	Members    Subjects   `json:"members"`
+	Statements Statements `json:"statements"`
+}
+type Policies []Policy
+
+// Statement describes which actions are allowed (or denied) on which resources in which projects.
+// An empty project list stands for AllProjects.
+type Statement struct {
+	ID        string    `json:"id,omitempty"`
+	Effect    Effect    `json:"effect"`
+	Resources Resources `json:"resources"`
+	Actions   Actions   `json:"actions,omitempty"`
+	// Role refers to a Role whose actions are added to Actions.
+	Role     string   `json:"role,omitempty"`
+	Projects Projects `json:"projects,omitempty"`
+}
+type Statements []Statement
+
+// Role is a named set of actions which statements can refer to.
+type Role struct {
+	ID      string  `json:"id"`
+	Name    string  `json:"name,omitempty"`
+	Actions Actions `json:"actions"`
+}
+type Roles []Role
+
+// RoleBinding makes Subject inherit every policy whose members include Role, in Project.
+// An empty Project stands for AllProjects.
+type RoleBinding struct {
+	Subject Subject `json:"subject"`
+	Role    string  `json:"role"`
+	Project Project `json:"project,omitempty"`
+}
+type RoleBindings []RoleBinding
+
+func MakePolicyMap(policies ...Policy) PolicyMap {
+	return PolicyMap{PoliciesKey: Policies(policies)}
+}
+
+func MakeRoleMap(roles Roles, bindings RoleBindings) RoleMap {
+	return RoleMap{
+		RolesKey:        roles,
+		RoleBindingsKey: bindings,
+	}
+}
+
+// Policies returns the engine-neutral policies carried in the map (if any).
+func (m PolicyMap) Policies() (Policies, bool) {
+	switch t := m[PoliciesKey].(type) {
+	case Policies:
+		return t, true
+	case []Policy:
+		return t, true
+	}
+	return nil, false
+}
+
+// Roles returns the engine-neutral roles carried in the map (if any).
+func (m RoleMap) Roles() (Roles, bool) {
+	switch t := m[RolesKey].(type) {
+	case Roles:
+		return t, true
+	case []Role:
+		return t, true
+	}
+	return nil, false
+}
+
+// RoleBindings returns the engine-neutral role bindings carried in the map (if any).
+func (m RoleMap) RoleBindings() (RoleBindings, bool) {
+	switch t := m[RoleBindingsKey].(type) {
+	case RoleBindings:
+		return t, true
+	case []RoleBinding:
+		return t, true
+	}
+	return nil, false
+}
+
+// RoleActions indexes the actions of the roles by role ID.
+func (r Roles) RoleActions() map[string]Actions {
+	m := make(map[string]Actions, len(r))
+	for _, role := range r {
+		m[role.ID] = role.Actions
+	}
+	return m
+}
+
+// EffectiveActions returns the statement actions merged with the actions of its role.
+func (s Statement) EffectiveActions(roleActions map[string]Actions) Actions {
+	if s.Role == "" {
+		return s.Actions
+	}
+	actions := make(Actions, 0, len(s.Actions)+len(roleActions[s.Role]))
+	actions = append(actions, s.Actions...)
+	actions = append(actions, roleActions[s.Role]...)
+	return actions
+}
+
+// EffectiveProjects returns the statement projects, or AllProjects when none are given.
+func (s Statement) EffectiveProjects() Projects {
+	if len(s.Projects) == 0 {
+		return Projects{AllProjects}
+	}
+	return s.Projects
+}
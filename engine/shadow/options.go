@@ -0,0 +1,49 @@
+package shadow
+
+import (
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+const (
+	DefaultTimeout     = 5 * time.Second
+	DefaultMaxInFlight = 64
+)
+
+type OptFunc func(*State)
+
+// WithDivergenceHandler sets a callback which receives every divergence between the primary and the candidate.
+func WithDivergenceHandler(handler DivergenceHandler) OptFunc {
+	return func(s *State) {
+		s.handler = handler
+	}
+}
+
+// WithTimeout bounds the time spent evaluating the candidate for a single check.
+func WithTimeout(timeout time.Duration) OptFunc {
+	return func(s *State) {
+		s.timeout = timeout
+	}
+}
+
+// WithMaxInFlight bounds the number of concurrent candidate evaluations,
+// checks above the limit are not shadowed and counted by State.Skipped.
+func WithMaxInFlight(n int) OptFunc {
+	return func(s *State) {
+		s.maxInFlight = n
+	}
+}
+
+// WithMirrorPolicies makes SetPolicies write the policies to the candidate too.
+func WithMirrorPolicies(mirror bool) OptFunc {
+	return func(s *State) {
+		s.mirrorPolicies = mirror
+	}
+}
+
+func WithLogger(logger log.Logger) OptFunc {
+	return func(s *State) {
+		s.log = log.NewHelper(log.With(logger, "module", "shadow.authz.engine"))
+	}
+}
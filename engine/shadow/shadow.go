@@ -0,0 +1,361 @@
+package shadow
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+// Divergence describes a check for which the candidate disagreed with the primary.
+type Divergence struct {
+	Operation string
+
+	Subjects engine.Subjects
+	Action   engine.Action
+	Resource engine.Resource
+	Project  engine.Project
+	Projects engine.Projects
+	Pairs    engine.Pairs
+
+	Primary   interface{}
+	Candidate interface{}
+	// CandidateError is set when the candidate failed to evaluate the check.
+	CandidateError error
+}
+
+func (d *Divergence) String() string {
+	return fmt.Sprintf("operation=%s subjects=%v action=%s resource=%s project=%s projects=%v pairs=%v primary=%v candidate=%v candidate_error=%v",
+		d.Operation, d.Subjects, d.Action, d.Resource, d.Project, d.Projects, d.Pairs, d.Primary, d.Candidate, d.CandidateError)
+}
+
+type DivergenceHandler func(ctx context.Context, d *Divergence)
+
+var _ engine.Engine = (*State)(nil)
+
+// State answers every check with the primary engine and evaluates the candidate
+// engine for the same check in the background, reporting the divergences.
+type State struct {
+	primary   engine.Engine
+	candidate engine.Engine
+
+	handler        DivergenceHandler
+	timeout        time.Duration
+	maxInFlight    int
+	mirrorPolicies bool
+
+	inFlight chan struct{}
+	wg       sync.WaitGroup
+	skipped  atomic.Uint64
+
+	log *log.Helper
+}
+
+func NewEngine(primary, candidate engine.Engine, opts ...OptFunc) *State {
+	s := &State{
+		primary:     primary,
+		candidate:   candidate,
+		timeout:     DefaultTimeout,
+		maxInFlight: DefaultMaxInFlight,
+		log:         log.NewHelper(log.With(log.DefaultLogger, "module", "shadow.authz.engine")),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.inFlight = make(chan struct{}, s.maxInFlight)
+
+	return s
+}
+
+func (s *State) Name() string {
+	return s.primary.Name()
+}
+
+func (s *State) ProjectsAuthorized(ctx context.Context, subjects engine.Subjects, action engine.Action, resource engine.Resource, projects engine.Projects) (engine.Projects, error) {
+	result, err := s.primary.ProjectsAuthorized(ctx, subjects, action, resource, projects)
+	if err != nil {
+		return nil, err
+	}
+
+	s.shadow(ctx, func(ctx context.Context) *Divergence {
+		candidate, err := s.candidate.ProjectsAuthorized(ctx, subjects, action, resource, projects)
+		if err == nil && sameSet(result, candidate) {
+			return nil
+		}
+		return &Divergence{
+			Operation:      "ProjectsAuthorized",
+			Subjects:       subjects,
+			Action:         action,
+			Resource:       resource,
+			Projects:       projects,
+			Primary:        result,
+			Candidate:      candidate,
+			CandidateError: err,
+		}
+	})
+
+	return result, nil
+}
+
+func (s *State) FilterAuthorizedPairs(ctx context.Context, subjects engine.Subjects, pairs engine.Pairs) (engine.Pairs, error) {
+	result, err := s.primary.FilterAuthorizedPairs(ctx, subjects, pairs)
+	if err != nil {
+		return nil, err
+	}
+
+	s.shadow(ctx, func(ctx context.Context) *Divergence {
+		candidate, err := s.candidate.FilterAuthorizedPairs(ctx, subjects, pairs)
+		if err == nil && sameSet(result, candidate) {
+			return nil
+		}
+		return &Divergence{
+			Operation:      "FilterAuthorizedPairs",
+			Subjects:       subjects,
+			Pairs:          pairs,
+			Primary:        result,
+			Candidate:      candidate,
+			CandidateError: err,
+		}
+	})
+
+	return result, nil
+}
+
+func (s *State) FilterAuthorizedProjects(ctx context.Context, subjects engine.Subjects) (engine.Projects, error) {
+	result, err := s.primary.FilterAuthorizedProjects(ctx, subjects)
+	if err != nil {
+		return nil, err
+	}
+
+	s.shadow(ctx, func(ctx context.Context) *Divergence {
+		candidate, err := s.candidate.FilterAuthorizedProjects(ctx, subjects)
+		if err == nil && sameSet(result, candidate) {
+			return nil
+		}
+		return &Divergence{
+			Operation:      "FilterAuthorizedProjects",
+			Subjects:       subjects,
+			Primary:        result,
+			Candidate:      candidate,
+			CandidateError: err,
+		}
+	})
+
+	return result, nil
+}
+
+func (s *State) IsAuthorized(ctx context.Context, subject engine.Subject, action engine.Action, resource engine.Resource, project engine.Project) (bool, error) {
+	allowed, err := s.primary.IsAuthorized(ctx, subject, action, resource, project)
+	if err != nil {
+		return false, err
+	}
+
+	s.shadow(ctx, func(ctx context.Context) *Divergence {
+		candidate, err := s.candidate.IsAuthorized(ctx, subject, action, resource, project)
+		if err == nil && allowed == candidate {
+			return nil
+		}
+		return &Divergence{
+			Operation:      "IsAuthorized",
+			Subjects:       engine.MakeSubjects(subject),
+			Action:         action,
+			Resource:       resource,
+			Project:        project,
+			Primary:        allowed,
+			Candidate:      candidate,
+			CandidateError: err,
+		}
+	})
+
+	return allowed, nil
+}
+
+func (s *State) Decide(ctx context.Context, subject engine.Subject, action engine.Action, resource engine.Resource, project engine.Project) (*engine.Decision, error) {
+	decision, err := s.primary.Decide(ctx, subject, action, resource, project)
+	if err != nil {
+		return nil, err
+	}
+
+	s.shadow(ctx, func(ctx context.Context) *Divergence {
+		candidate, err := s.candidate.Decide(ctx, subject, action, resource, project)
+		if err == nil && decision.Allowed() == candidate.Allowed() {
+			return nil
+		}
+		return &Divergence{
+			Operation:      "Decide",
+			Subjects:       engine.MakeSubjects(subject),
+			Action:         action,
+			Resource:       resource,
+			Project:        project,
+			Primary:        decision,
+			Candidate:      candidate,
+			CandidateError: err,
+		}
+	})
+
+	return decision, nil
+}
+
+func (s *State) BatchIsAuthorized(ctx context.Context, requests engine.Requests) ([]bool, error) {
+	result, err := s.primary.BatchIsAuthorized(ctx, requests)
+	if err != nil {
+		return nil, err
+	}
+
+	s.shadowBatch(ctx, requests, result)
+
+	return result, nil
+}
+
+// SetPolicies writes the policies to the primary, and to the candidate as well when mirroring is enabled.
+func (s *State) SetPolicies(ctx context.Context, policies engine.PolicyMap, roles engine.RoleMap) error {
+	return s.write("set policies", func(e engine.Engine) error {
+		return e.SetPolicies(ctx, policies, roles)
+	})
+}
+
+func (s *State) AddPolicies(ctx context.Context, policies engine.Policies) error {
+	return s.write("add policies", func(e engine.Engine) error {
+		return e.AddPolicies(ctx, policies)
+	})
+}
+
+func (s *State) RemovePolicies(ctx context.Context, policies engine.Policies) error {
+	return s.write("remove policies", func(e engine.Engine) error {
+		return e.RemovePolicies(ctx, policies)
+	})
+}
+
+func (s *State) AddRoleBindings(ctx context.Context, bindings engine.RoleBindings) error {
+	return s.write("add role bindings", func(e engine.Engine) error {
+		return e.AddRoleBindings(ctx, bindings)
+	})
+}
+
+func (s *State) RemoveRoleBindings(ctx context.Context, bindings engine.RoleBindings) error {
+	return s.write("remove role bindings", func(e engine.Engine) error {
+		return e.RemoveRoleBindings(ctx, bindings)
+	})
+}
+
+// write applies a policy change to the primary, and to the candidate as well when mirroring is enabled.
+// Candidate failures are only logged.
+func (s *State) write(operation string, fn func(e engine.Engine) error) error {
+	if err := fn(s.primary); err != nil {
+		return err
+	}
+
+	if s.mirrorPolicies {
+		if err := fn(s.candidate); err != nil {
+			s.log.Errorf("failed to %s on candidate: %v", operation, err)
+		}
+	}
+
+	return nil
+}
+
+// SetCandidatePolicies writes the policies to the candidate only.
+func (s *State) SetCandidatePolicies(ctx context.Context, policies engine.PolicyMap, roles engine.RoleMap) error {
+	return s.candidate.SetPolicies(ctx, policies, roles)
+}
+
+// Skipped returns how many checks were not compared with the candidate, as WithMaxInFlight
+// evaluations were already running.
+func (s *State) Skipped() uint64 {
+	return s.skipped.Load()
+}
+
+// Wait blocks until every background candidate evaluation has finished.
+func (s *State) Wait() {
+	s.wg.Wait()
+}
+
+func (s *State) shadowBatch(ctx context.Context, requests engine.Requests, result []bool) {
+	s.shadow(ctx, func(ctx context.Context) *Divergence {
+		candidate, err := s.candidate.BatchIsAuthorized(ctx, requests)
+		if err != nil || len(candidate) != len(result) {
+			return &Divergence{
+				Operation:      "BatchIsAuthorized",
+				Primary:        result,
+				Candidate:      candidate,
+				CandidateError: err,
+			}
+		}
+
+		// every diverging request is reported on its own
+		for i, r := range requests {
+			if result[i] == candidate[i] {
+				continue
+			}
+			s.report(ctx, &Divergence{
+				Operation: "BatchIsAuthorized",
+				Subjects:  engine.MakeSubjects(r.Subject),
+				Action:    r.Action,
+				Resource:  r.Resource,
+				Project:   r.Project,
+				Primary:   result[i],
+				Candidate: candidate[i],
+			})
+		}
+		return nil
+	})
+}
+
+func (s *State) shadow(ctx context.Context, eval func(ctx context.Context) *Divergence) {
+	select {
+	case s.inFlight <- struct{}{}:
+	default:
+		skipped := s.skipped.Add(1)
+		s.log.Warnf("too many candidate evaluations in flight, skipping shadow check (%d skipped so far)", skipped)
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer func() {
+			<-s.inFlight
+			s.wg.Done()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.timeout)
+		defer cancel()
+
+		if d := eval(ctx); d != nil {
+			s.report(ctx, d)
+		}
+	}()
+}
+
+func (s *State) report(ctx context.Context, d *Divergence) {
+	if d.CandidateError != nil {
+		s.log.Errorf("shadow candidate %s failed: %s", s.candidate.Name(), d)
+	} else {
+		s.log.Warnf("shadow divergence between %s and %s: %s", s.primary.Name(), s.candidate.Name(), d)
+	}
+
+	if s.handler != nil {
+		s.handler(ctx, d)
+	}
+}
+
+func sameSet[S ~[]T, T comparable](a, b S) bool {
+	as := make(map[T]bool, len(a))
+	for _, v := range a {
+		as[v] = true
+	}
+	bs := make(map[T]bool, len(b))
+	for _, v := range b {
+		if !as[v] {
+			return false
+		}
+		bs[v] = true
+	}
+	return len(as) == len(bs)
+}
@@ -0,0 +1,193 @@
+package shadow
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-authz/engine"
+	"github.com/tx7do/kratos-authz/engine/noop"
+)
+
+// denyEngine denies everything, and fails for the "error" subject.
+type denyEngine struct {
+	policies engine.PolicyMap
+	added    engine.Policies
+}
+
+var errCandidate = errors.New("candidate failure")
+
+func (e *denyEngine) Name() string {
+	return "deny"
+}
+
+func (e *denyEngine) ProjectsAuthorized(_ context.Context, _ engine.Subjects, _ engine.Action, _ engine.Resource, _ engine.Projects) (engine.Projects, error) {
+	return engine.Projects{"project1"}, nil
+}
+
+func (e *denyEngine) FilterAuthorizedPairs(_ context.Context, _ engine.Subjects, _ engine.Pairs) (engine.Pairs, error) {
+	return engine.Pairs{}, nil
+}
+
+func (e *denyEngine) FilterAuthorizedProjects(_ context.Context, _ engine.Subjects) (engine.Projects, error) {
+	return engine.Projects{}, nil
+}
+
+func (e *denyEngine) IsAuthorized(_ context.Context, subject engine.Subject, _ engine.Action, _ engine.Resource, _ engine.Project) (bool, error) {
+	if subject == "error" {
+		return false, errCandidate
+	}
+	return false, nil
+}
+
+func (e *denyEngine) Decide(_ context.Context, _ engine.Subject, _ engine.Action, _ engine.Resource, _ engine.Project) (*engine.Decision, error) {
+	return engine.MakeDenyDecision(e.Name(), "denied"), nil
+}
+
+func (e *denyEngine) BatchIsAuthorized(_ context.Context, requests engine.Requests) ([]bool, error) {
+	return make([]bool, len(requests)), nil
+}
+
+func (e *denyEngine) SetPolicies(_ context.Context, policies engine.PolicyMap, _ engine.RoleMap) error {
+	e.policies = policies
+	return nil
+}
+
+func (e *denyEngine) AddPolicies(_ context.Context, policies engine.Policies) error {
+	e.added = append(e.added, policies...)
+	return nil
+}
+
+func (e *denyEngine) RemovePolicies(_ context.Context, _ engine.Policies) error {
+	return nil
+}
+
+func (e *denyEngine) AddRoleBindings(_ context.Context, _ engine.RoleBindings) error {
+	return nil
+}
+
+func (e *denyEngine) RemoveRoleBindings(_ context.Context, _ engine.RoleBindings) error {
+	return nil
+}
+
+type collector struct {
+	mu          sync.Mutex
+	divergences []*Divergence
+}
+
+func (c *collector) handle(_ context.Context, d *Divergence) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.divergences = append(c.divergences, d)
+}
+
+func TestShadow(t *testing.T) {
+	primary, _ := noop.NewEngine(t.Context())
+	candidate := &denyEngine{}
+	c := &collector{}
+
+	s := NewEngine(primary, candidate, WithDivergenceHandler(c.handle))
+
+	allowed, err := s.IsAuthorized(t.Context(), "bobo", "GET", "/api/users", "project1")
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+
+	_, err = s.IsAuthorized(t.Context(), "error", "GET", "/api/users", "")
+	assert.Nil(t, err)
+
+	// noop and deny agree on empty results
+	_, err = s.FilterAuthorizedPairs(t.Context(), engine.MakeSubjects("bobo"), engine.MakePairs(engine.MakePair("/api/users", "GET")))
+	assert.Nil(t, err)
+
+	r, err := s.BatchIsAuthorized(t.Context(), engine.MakeRequests(
+		engine.MakeRequest("alice", "GET", "/api/users", ""),
+		engine.MakeRequest("bobo", "GET", "/api/dept", ""),
+	))
+	assert.Nil(t, err)
+	assert.Equal(t, []bool{true, true}, r)
+
+	s.Wait()
+
+	assert.Len(t, c.divergences, 4)
+
+	byOperation := map[string][]*Divergence{}
+	for _, d := range c.divergences {
+		byOperation[d.Operation] = append(byOperation[d.Operation], d)
+	}
+
+	d := byOperation["IsAuthorized"]
+	assert.Len(t, d, 2)
+	for _, x := range d {
+		if x.Subjects[0] == "error" {
+			assert.ErrorIs(t, x.CandidateError, errCandidate)
+			continue
+		}
+		assert.Equal(t, engine.Action("GET"), x.Action)
+		assert.Equal(t, engine.Resource("/api/users"), x.Resource)
+		assert.Equal(t, engine.Project("project1"), x.Project)
+		assert.Equal(t, true, x.Primary)
+		assert.Equal(t, false, x.Candidate)
+	}
+
+	assert.Len(t, byOperation["BatchIsAuthorized"], 2)
+}
+
+func TestSetPolicies(t *testing.T) {
+	primary, _ := noop.NewEngine(t.Context())
+	candidate := &denyEngine{}
+	policies := engine.PolicyMap{"policies": "p"}
+
+	s := NewEngine(primary, candidate)
+	assert.Nil(t, s.SetPolicies(t.Context(), policies, nil))
+	assert.Nil(t, candidate.policies)
+
+	assert.Nil(t, s.SetCandidatePolicies(t.Context(), policies, nil))
+	assert.Equal(t, policies, candidate.policies)
+
+	added := engine.Policies{{ID: "p1", Members: engine.MakeSubjects("bobo")}}
+	assert.Nil(t, s.AddPolicies(t.Context(), added))
+	assert.Nil(t, candidate.added)
+
+	candidate.policies = nil
+	s = NewEngine(primary, candidate, WithMirrorPolicies(true))
+	assert.Nil(t, s.SetPolicies(t.Context(), policies, nil))
+	assert.Equal(t, policies, candidate.policies)
+
+	assert.Nil(t, s.AddPolicies(t.Context(), added))
+	assert.Equal(t, added, candidate.added)
+}
+
+// blockingEngine holds the IsAuthorized checks until release is closed.
+type blockingEngine struct {
+	denyEngine
+	release chan struct{}
+}
+
+func (e *blockingEngine) IsAuthorized(ctx context.Context, subject engine.Subject, action engine.Action, resource engine.Resource, project engine.Project) (bool, error) {
+	<-e.release
+	return e.denyEngine.IsAuthorized(ctx, subject, action, resource, project)
+}
+
+func TestMaxInFlight(t *testing.T) {
+	primary, _ := noop.NewEngine(t.Context())
+	candidate := &blockingEngine{release: make(chan struct{})}
+	c := &collector{}
+
+	s := NewEngine(primary, candidate, WithMaxInFlight(1), WithDivergenceHandler(c.handle))
+
+	for range 3 {
+		allowed, err := s.IsAuthorized(t.Context(), "bobo", "GET", "/api/users", "project1")
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+	}
+	assert.EqualValues(t, 2, s.Skipped())
+
+	close(candidate.release)
+	s.Wait()
+
+	assert.Len(t, c.divergences, 1)
+	assert.EqualValues(t, 2, s.Skipped())
+}
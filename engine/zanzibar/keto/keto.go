@@ -14,6 +14,13 @@ import (
 	acl "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
 )
 
+// SubjectSet is the subjects having Relation with Object in Namespace, <namespace>:<object>#<relation>.
+type SubjectSet struct {
+	Namespace string
+	Object    string
+	Relation  string
+}
+
 type Client struct {
 	checkServiceClient  acl.CheckServiceClient
 	readServiceClient   acl.ReadServiceClient
@@ -58,6 +65,33 @@ func (c *Client) CreateRelationTuple(ctx context.Context, namespace, object, rel
 	}
 }
 
+func (c *Client) DeleteRelationTuple(ctx context.Context, namespace, object, relation, subject string) error {
+	if c.useGRPC {
+		return c.grpcDeleteRelationTuple(ctx, namespace, object, relation, subject)
+	} else {
+		return c.restDeleteRelationTuple(ctx, namespace, object, relation, subject)
+	}
+}
+
+// CreateSubjectSetTuple grants relation with object to the subjects of the subject set.
+func (c *Client) CreateSubjectSetTuple(ctx context.Context, namespace, object, relation string, subjectSet SubjectSet) error {
+	if c.useGRPC {
+		return c.grpcTransactRelationTuple(ctx, acl.RelationTupleDelta_ACTION_INSERT, namespace, object, relation,
+			acl.NewSubjectSet(subjectSet.Namespace, subjectSet.Object, subjectSet.Relation))
+	} else {
+		return c.restCreateSubjectSetTuple(ctx, namespace, object, relation, subjectSet)
+	}
+}
+
+func (c *Client) DeleteSubjectSetTuple(ctx context.Context, namespace, object, relation string, subjectSet SubjectSet) error {
+	if c.useGRPC {
+		return c.grpcTransactRelationTuple(ctx, acl.RelationTupleDelta_ACTION_DELETE, namespace, object, relation,
+			acl.NewSubjectSet(subjectSet.Namespace, subjectSet.Object, subjectSet.Relation))
+	} else {
+		return c.restDeleteSubjectSetTuple(ctx, namespace, object, relation, subjectSet)
+	}
+}
+
 func (c *Client) createGrpcReadClient(uri string) {
 	conn, err := grpc.NewClient(uri, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
@@ -116,6 +150,56 @@ func (c *Client) restCreateRelationTuple(ctx context.Context, namespace, object,
 	return nil
 }
 
+func (c *Client) restDeleteRelationTuple(ctx context.Context, namespace, object, relation, subject string) error {
+	r, err := c.writeClient.RelationshipApi.DeleteRelationships(ctx).
+		Namespace(namespace).
+		Object(object).
+		Relation(relation).
+		SubjectId(subject).
+		Execute()
+	if err != nil {
+		log.Errorf("restDeleteRelationTuple error: [%s][%v]", err.Error(), r)
+		return err
+	}
+
+	return nil
+}
+
+func (c *Client) restCreateSubjectSetTuple(ctx context.Context, namespace, object, relation string, subjectSet SubjectSet) error {
+	relationQuery := *client.NewCreateRelationshipBody()
+	relationQuery.SetNamespace(namespace)
+	relationQuery.SetObject(object)
+	relationQuery.SetRelation(relation)
+	relationQuery.SetSubjectSet(*client.NewSubjectSet(subjectSet.Namespace, subjectSet.Object, subjectSet.Relation))
+
+	_, r, err := c.writeClient.RelationshipApi.CreateRelationship(ctx).
+		CreateRelationshipBody(relationQuery).
+		Execute()
+	if err != nil {
+		log.Errorf("restCreateSubjectSetTuple error: [%s][%v]", err.Error(), r)
+		return err
+	}
+
+	return nil
+}
+
+func (c *Client) restDeleteSubjectSetTuple(ctx context.Context, namespace, object, relation string, subjectSet SubjectSet) error {
+	r, err := c.writeClient.RelationshipApi.DeleteRelationships(ctx).
+		Namespace(namespace).
+		Object(object).
+		Relation(relation).
+		SubjectSetNamespace(subjectSet.Namespace).
+		SubjectSetObject(subjectSet.Object).
+		SubjectSetRelation(subjectSet.Relation).
+		Execute()
+	if err != nil {
+		log.Errorf("restDeleteSubjectSetTuple error: [%s][%v]", err.Error(), r)
+		return err
+	}
+
+	return nil
+}
+
 func (c *Client) restCheckPermission(ctx context.Context, namespace, object, relation, subject string) (bool, error) {
 	check, r, err := c.readClient.PermissionApi.CheckPermission(ctx).
 		Namespace(namespace).
@@ -151,6 +235,46 @@ func (c *Client) grpcCreateRelationTuple(ctx context.Context, namespace, object,
 	return err
 }
 
+func (c *Client) grpcDeleteRelationTuple(ctx context.Context, namespace, object, relation, subject string) error {
+	response, err := c.writeServiceClient.TransactRelationTuples(ctx, &acl.TransactRelationTuplesRequest{
+		RelationTupleDeltas: []*acl.RelationTupleDelta{
+			{
+				Action: acl.RelationTupleDelta_ACTION_DELETE,
+				RelationTuple: &acl.RelationTuple{
+					Namespace: namespace,
+					Object:    object,
+					Relation:  relation,
+					Subject:   acl.NewSubjectID(subject),
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.Errorf("grpcDeleteRelationTuple error: [%s][%v]", err.Error(), response)
+	}
+	return err
+}
+
+func (c *Client) grpcTransactRelationTuple(ctx context.Context, action acl.RelationTupleDelta_Action, namespace, object, relation string, subject *acl.Subject) error {
+	response, err := c.writeServiceClient.TransactRelationTuples(ctx, &acl.TransactRelationTuplesRequest{
+		RelationTupleDeltas: []*acl.RelationTupleDelta{
+			{
+				Action: action,
+				RelationTuple: &acl.RelationTuple{
+					Namespace: namespace,
+					Object:    object,
+					Relation:  relation,
+					Subject:   subject,
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.Errorf("grpcTransactRelationTuple error: [%s][%v]", err.Error(), response)
+	}
+	return err
+}
+
 func (c *Client) grpcGetCheck(ctx context.Context, namespace, object, relation, subject string) (bool, error) {
 	response, err := c.checkServiceClient.Check(ctx, &acl.CheckRequest{
 		Tuple: &acl.RelationTuple{
@@ -3,6 +3,8 @@ package openfga
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strconv"
 
 	"github.com/go-kratos/kratos/v2/log"
 	"github.com/google/uuid"
@@ -12,16 +14,23 @@ import (
 	"github.com/openfga/go-sdk/credentials"
 )
 
+// DefaultMaxBatchCheckSize is the number of checks of a BatchCheck call accepted by default by
+// the OpenFGA server, see its maxChecksPerBatchCheck setting.
+const DefaultMaxBatchCheckSize = 50
+
 type Client struct {
 	fgaClient *client.OpenFgaClient
 
 	apiUrl, storeId string
 	credentials     credentials.Credentials
+
+	maxBatchCheckSize int
 }
 
 func NewClient(opts ...ClientOption) *Client {
 	cli := &Client{
-		credentials: credentials.Credentials{},
+		credentials:       credentials.Credentials{},
+		maxBatchCheckSize: DefaultMaxBatchCheckSize,
 	}
 
 	cli.init(opts...)
@@ -100,6 +109,67 @@ func (c *Client) GetCheck(ctx context.Context, object, relation, subject string)
 	return *data.Allowed, nil
 }
 
+type CheckTuple struct {
+	Object   string
+	Relation string
+	Subject  string
+}
+
+// BatchGetCheck runs the checks with server-side BatchCheck calls of at most WithMaxBatchCheckSize
+// checks each, the result has the same order as tuples. The failure of a single check fails the batch.
+func (c *Client) BatchGetCheck(ctx context.Context, tuples []CheckTuple) ([]bool, error) {
+	result := make([]bool, len(tuples))
+
+	size := max(c.maxBatchCheckSize, 1)
+	for start := 0; start < len(tuples); start += size {
+		end := min(start+size, len(tuples))
+		if err := c.batchCheck(ctx, tuples[start:end], result[start:end]); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// batchCheck runs the checks of tuples with a single BatchCheck call, into result.
+func (c *Client) batchCheck(ctx context.Context, tuples []CheckTuple, result []bool) error {
+	checks := make([]openfga.BatchCheckItem, 0, len(tuples))
+	for i, t := range tuples {
+		checks = append(checks, openfga.BatchCheckItem{
+			TupleKey: openfga.CheckRequestTupleKey{
+				User:     t.Subject,
+				Relation: t.Relation,
+				Object:   t.Object,
+			},
+			CorrelationId: strconv.Itoa(i),
+		})
+	}
+
+	data, response, err := c.fgaClient.OpenFgaApi.
+		BatchCheck(ctx, c.storeId).
+		Body(openfga.BatchCheckRequest{Checks: checks}).
+		Execute()
+	if err != nil {
+		log.Errorf("BatchGetCheck error: [%s][%v]", err.Error(), response)
+		return err
+	}
+
+	results := data.GetResult()
+	for i, t := range tuples {
+		r, ok := results[strconv.Itoa(i)]
+		if !ok {
+			return fmt.Errorf("openfga: no result for the check of %s#%s@%s", t.Object, t.Relation, t.Subject)
+		}
+		if r.Error != nil {
+			log.Errorf("BatchGetCheck error: [%s#%s@%s][%v]", t.Object, t.Relation, t.Subject, r.Error.GetMessage())
+			return fmt.Errorf("openfga: check of %s#%s@%s failed: %s", t.Object, t.Relation, t.Subject, r.Error.GetMessage())
+		}
+		result[i] = r.GetAllowed()
+	}
+
+	return nil
+}
+
 func (c *Client) ListStore(ctx context.Context) (*[]openfga.Store, error) {
 	stores, response, err := c.fgaClient.OpenFgaApi.ListStores(ctx).Execute()
 	if err != nil {
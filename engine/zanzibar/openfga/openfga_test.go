@@ -1,10 +1,16 @@
 package openfga
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
+	openfga "github.com/openfga/go-sdk"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestClient(t *testing.T) {
@@ -84,3 +90,60 @@ func doTestData(t *testing.T, cli *Client) {
 		})
 	}
 }
+
+// batchCheckServer serves the store list and the BatchCheck endpoint, the checks of the "error"
+// relation fail and the other ones are allowed for the "user:anne" subject.
+type batchCheckServer struct {
+	batches []int
+}
+
+func (b *batchCheckServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if !strings.HasSuffix(r.URL.Path, "/batch-check") {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"stores": []map[string]string{{"id": "01ARZ3NDEKTSV4RRFFQ69G5FAV", "name": "test"}},
+		})
+		return
+	}
+
+	var request openfga.BatchCheckRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	b.batches = append(b.batches, len(request.Checks))
+
+	result := map[string]openfga.BatchCheckSingleResult{}
+	for _, check := range request.Checks {
+		if check.TupleKey.Relation == "error" {
+			result[check.CorrelationId] = openfga.BatchCheckSingleResult{Error: &openfga.CheckError{Message: openfga.PtrString("boom")}}
+			continue
+		}
+		result[check.CorrelationId] = openfga.BatchCheckSingleResult{Allowed: openfga.PtrBool(check.TupleKey.User == "user:anne")}
+	}
+	_ = json.NewEncoder(w).Encode(openfga.BatchCheckResponse{Result: &result})
+}
+
+func TestClient_BatchGetCheck(t *testing.T) {
+	handler := &batchCheckServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	cli := NewClient(WithApiUrl(server.URL), WithStoreId("01ARZ3NDEKTSV4RRFFQ69G5FAV"), WithMaxBatchCheckSize(2))
+
+	tuples := []CheckTuple{
+		{Object: "document:1", Relation: "reader", Subject: "user:anne"},
+		{Object: "document:2", Relation: "reader", Subject: "user:bob"},
+		{Object: "document:3", Relation: "reader", Subject: "user:bob"},
+		{Object: "document:4", Relation: "reader", Subject: "user:anne"},
+		{Object: "document:5", Relation: "reader", Subject: "user:anne"},
+	}
+	allowed, err := cli.BatchGetCheck(t.Context(), tuples)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, false, false, true, true}, allowed)
+	assert.Equal(t, []int{2, 2, 1}, handler.batches)
+
+	_, err = cli.BatchGetCheck(t.Context(), append(tuples, CheckTuple{Object: "document:6", Relation: "error", Subject: "user:anne"}))
+	assert.ErrorContains(t, err, "boom")
+}
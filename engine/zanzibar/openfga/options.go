@@ -18,6 +18,16 @@ func WithStoreId(storeId string) ClientOption {
 	}
 }
 
+// WithMaxBatchCheckSize sets the number of checks sent with each BatchCheck call, it must not
+// exceed the maxChecksPerBatchCheck setting of the server.
+func WithMaxBatchCheckSize(size int) ClientOption {
+	return func(c *Client) {
+		if size > 0 {
+			c.maxBatchCheckSize = size
+		}
+	}
+}
+
 func WithToken(token string) ClientOption {
 	return func(c *Client) {
 		if token != "" {
@@ -0,0 +1,125 @@
+package zanzibar
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+// MemberRelation is the relation written for role bindings: <role>#member@<subject>.
+const MemberRelation = "member"
+
+// RoleType is the type of the role objects whose ID has no type, role:<id>.
+const RoleType = "role"
+
+var ErrDenyNotSupported = errors.New("zanzibar: deny statements are not supported")
+
+// subjectSet is the subjects having relation with object in namespace.
+type subjectSet struct {
+	namespace string
+	object    string
+	relation  string
+}
+
+// relationTuple maps onto the checks done by IsAuthorized: the project is the
+// namespace, the resource the object and the action the relation. The members of a
+// role are granted through the subject set of the role rather than a subject.
+type relationTuple struct {
+	namespace  string
+	object     string
+	relation   string
+	subject    string
+	subjectSet subjectSet
+}
+
+func (t relationTuple) String() string {
+	if t.subjectSet.relation != "" {
+		return fmt.Sprintf("%s:%s#%s@(%s:%s#%s)", t.namespace, t.object, t.relation,
+			t.subjectSet.namespace, t.subjectSet.object, t.subjectSet.relation)
+	}
+	return fmt.Sprintf("%s:%s#%s@%s", t.namespace, t.object, t.relation, t.subject)
+}
+
+// relationTuples translates the engine-neutral policy model into relation tuples. The policy
+// members bound to by the role bindings are roles: their tuples grant the members of the role,
+// <role>#member, in the namespace of the statement and, for a project, in every project.
+func relationTuples(policies engine.Policies, roles engine.Roles, bindings engine.RoleBindings) ([]relationTuple, error) {
+	var tuples []relationTuple
+
+	bound := make(map[string]bool, len(bindings))
+	for _, binding := range bindings {
+		bound[binding.Role] = true
+	}
+
+	roleActions := roles.RoleActions()
+	for _, policy := range policies {
+		for _, statement := range policy.Statements {
+			if statement.Effect != engine.EffectAllow {
+				return nil, fmt.Errorf("%w: policy %s", ErrDenyNotSupported, policy.ID)
+			}
+			for _, project := range statement.EffectiveProjects() {
+				ns := namespace(project)
+				for _, member := range policy.Members {
+					for _, resource := range statement.Resources {
+						for _, action := range statement.EffectiveActions(roleActions) {
+							t := relationTuple{namespace: ns, object: string(resource), relation: string(action)}
+							if !bound[string(member)] {
+								t.subject = string(member)
+								tuples = append(tuples, t)
+								continue
+							}
+
+							t.subjectSet = subjectSet{namespace: ns, object: roleObject(string(member)), relation: MemberRelation}
+							tuples = append(tuples, t)
+							if ns != "" {
+								t.subjectSet.namespace = ""
+								tuples = append(tuples, t)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	for _, binding := range bindings {
+		tuples = append(tuples, relationTuple{
+			namespace: namespace(binding.Project),
+			object:    roleObject(binding.Role),
+			relation:  MemberRelation,
+			subject:   string(binding.Subject),
+		})
+	}
+
+	return tuples, nil
+}
+
+// namespace maps a project onto the namespace IsAuthorized checks for it.
+func namespace(project engine.Project) string {
+	if project == engine.AllProjects {
+		return ""
+	}
+	return string(project)
+}
+
+// roleObject returns the object of a role, the ID of the role with the RoleType when it has no type.
+func roleObject(role string) string {
+	if strings.Contains(role, ":") {
+		return role
+	}
+	return RoleType + ":" + role
+}
+
+// openfgaObject returns the OpenFGA object of an object in a namespace, the project being part
+// of the object ID as OpenFGA has no namespaces: <type>:<namespace>/<id>.
+func openfgaObject(namespace, object string) string {
+	if namespace == "" {
+		return object
+	}
+	if i := strings.Index(object, ":"); i >= 0 {
+		return object[:i+1] + namespace + "/" + object[i+1:]
+	}
+	return namespace + "/" + object
+}
@@ -3,6 +3,11 @@ package zanzibar
 import (
 	"context"
 	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/tx7do/kratos-authz/engine"
 	"github.com/tx7do/kratos-authz/engine/zanzibar/keto"
@@ -29,6 +34,13 @@ var _ engine.Engine = (*State)(nil)
 type State struct {
 	ketoClient    *keto.Client
 	openfgaClient *openfga.Client
+
+	// the policies and role bindings written through the engine, the roles of the last SetPolicies
+	// call resolve the statement roles of the added policies
+	mu       sync.Mutex
+	roles    engine.Roles
+	policies engine.Policies
+	bindings engine.RoleBindings
 }
 
 func NewEngine(_ context.Context, opts ...OptFunc) (*State, error) {
@@ -62,16 +74,260 @@ func (s *State) FilterAuthorizedProjects(_ context.Context, _ engine.Subjects) (
 }
 
 func (s *State) IsAuthorized(ctx context.Context, subject engine.Subject, action engine.Action, resource engine.Resource, project engine.Project) (bool, error) {
+	decision, err := s.Decide(ctx, subject, action, resource, project)
+	if err != nil {
+		return false, err
+	}
+	return decision.Allowed(), nil
+}
+
+func (s *State) Decide(ctx context.Context, subject engine.Subject, action engine.Action, resource engine.Resource, project engine.Project) (*engine.Decision, error) {
+	start := time.Now()
+
+	var (
+		allow bool
+		tuple string
+		err   error
+	)
 	if s.ketoClient != nil {
-		allow, err := s.ketoClient.GetCheck(ctx, string(project), string(resource), string(action), string(subject))
-		return allow, err
+		namespace := string(project)
+		allow, err = s.ketoClient.GetCheck(ctx, namespace, string(resource), string(action), string(subject))
+		if err == nil && !allow && project != "" {
+			// the statements without projects grant every project
+			namespace = ""
+			allow, err = s.ketoClient.GetCheck(ctx, namespace, string(resource), string(action), string(subject))
+		}
+		tuple = fmt.Sprintf("%s:%s#%s@%s", namespace, resource, action, subject)
 	} else if s.openfgaClient != nil {
-		allow, err := s.openfgaClient.GetCheck(ctx, string(resource), string(action), string(subject))
-		return allow, err
+		object := openfgaObject(string(project), string(resource))
+		allow, err = s.openfgaClient.GetCheck(ctx, object, string(action), string(subject))
+		if err == nil && !allow && project != "" {
+			// the statements without projects grant every project
+			object = string(resource)
+			allow, err = s.openfgaClient.GetCheck(ctx, object, string(action), string(subject))
+		}
+		tuple = fmt.Sprintf("%s#%s@%s", object, action, subject)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var decision *engine.Decision
+	if allow {
+		decision = engine.MakeAllowDecision(s.Name(), tuple)
+	} else {
+		decision = engine.MakeDenyDecision(s.Name(), fmt.Sprintf("relation %s not found", tuple))
 	}
-	return false, nil
+	decision.Duration = time.Since(start)
+
+	return decision, nil
 }
 
-func (s *State) SetPolicies(_ context.Context, _ engine.PolicyMap, _ engine.RoleMap) error {
+func (s *State) BatchIsAuthorized(ctx context.Context, requests engine.Requests) ([]bool, error) {
+	result := make([]bool, len(requests))
+	if len(requests) == 0 {
+		return result, nil
+	}
+
+	if s.openfgaClient != nil && s.ketoClient == nil {
+		// the requests of a project are checked in the project and in every project
+		tuples := make([]openfga.CheckTuple, 0, 2*len(requests))
+		checks := make([]int, 0, 2*len(requests))
+		for i, r := range requests {
+			tuples = append(tuples, openfga.CheckTuple{
+				Object:   openfgaObject(string(r.Project), string(r.Resource)),
+				Relation: string(r.Action),
+				Subject:  string(r.Subject),
+			})
+			checks = append(checks, i)
+			if r.Project != "" {
+				tuples = append(tuples, openfga.CheckTuple{
+					Object:   string(r.Resource),
+					Relation: string(r.Action),
+					Subject:  string(r.Subject),
+				})
+				checks = append(checks, i)
+			}
+		}
+
+		allowed, err := s.openfgaClient.BatchGetCheck(ctx, tuples)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range checks {
+			result[i] = result[i] || allowed[j]
+		}
+		return result, nil
+	}
+
+	var err error
+	for i, r := range requests {
+		if result[i], err = s.IsAuthorized(ctx, r.Subject, r.Action, r.Resource, r.Project); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// SetPolicies writes the relation tuples of the engine-neutral policies, other policy data is ignored.
+// The tuples of the previous SetPolicies call and of the later writes which are not in the new policies
+// are deleted, the tuples stored by other means are left untouched.
+func (s *State) SetPolicies(ctx context.Context, policyMap engine.PolicyMap, roleMap engine.RoleMap) error {
+	policies, _ := policyMap.Policies()
+	roles, _ := roleMap.Roles()
+	bindings, _ := roleMap.RoleBindings()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := relationTuples(s.policies, s.roles, s.bindings)
+	if err != nil {
+		return err
+	}
+	tuples, err := relationTuples(policies, roles, bindings)
+	if err != nil {
+		return err
+	}
+
+	s.roles, s.policies, s.bindings = roles, slices.Clone(policies), slices.Clone(bindings)
+
+	return s.replaceTuples(ctx, current, tuples)
+}
+
+func (s *State) AddPolicies(ctx context.Context, policies engine.Policies) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.update(ctx, append(slices.Clone(s.policies), policies...), s.bindings, nil)
+}
+
+func (s *State) RemovePolicies(ctx context.Context, policies engine.Policies) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed, err := relationTuples(policies, s.roles, s.bindings)
+	if err != nil {
+		return err
+	}
+
+	remaining := slices.DeleteFunc(slices.Clone(s.policies), func(p engine.Policy) bool {
+		return slices.ContainsFunc(policies, func(removed engine.Policy) bool { return reflect.DeepEqual(p, removed) })
+	})
+	return s.update(ctx, remaining, s.bindings, removed)
+}
+
+// AddRoleBindings writes the bindings, the tuples of the policies of a newly bound role are
+// rewritten to grant the members of the role.
+func (s *State) AddRoleBindings(ctx context.Context, bindings engine.RoleBindings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.update(ctx, s.policies, append(slices.Clone(s.bindings), bindings...), nil)
+}
+
+func (s *State) RemoveRoleBindings(ctx context.Context, bindings engine.RoleBindings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed, _ := relationTuples(nil, nil, bindings)
+	remaining := slices.DeleteFunc(slices.Clone(s.bindings), func(b engine.RoleBinding) bool {
+		return slices.Contains(bindings, b)
+	})
+	return s.update(ctx, s.policies, remaining, removed)
+}
+
+// update replaces the policies and role bindings of the engine, the tuples which are no longer
+// produced, and the removed ones, are deleted and the new ones written.
+func (s *State) update(ctx context.Context, policies engine.Policies, bindings engine.RoleBindings, removed []relationTuple) error {
+	current, err := relationTuples(s.policies, s.roles, s.bindings)
+	if err != nil {
+		return err
+	}
+	tuples, err := relationTuples(policies, s.roles, bindings)
+	if err != nil {
+		return err
+	}
+
+	s.policies, s.bindings = policies, bindings
+
+	return s.replaceTuples(ctx, append(current, removed...), tuples)
+}
+
+// replaceTuples deletes the current tuples which are not in tuples, and writes the new ones.
+func (s *State) replaceTuples(ctx context.Context, current, tuples []relationTuple) error {
+	if err := s.deleteTuples(ctx, difference(current, tuples)); err != nil {
+		return err
+	}
+	return s.writeTuples(ctx, difference(tuples, current))
+}
+
+// difference returns the tuples of a which are not in b, once each.
+func difference(a, b []relationTuple) []relationTuple {
+	seen := make(map[relationTuple]bool, len(b))
+	for _, t := range b {
+		seen[t] = true
+	}
+
+	var result []relationTuple
+	for _, t := range a {
+		if !seen[t] {
+			seen[t] = true
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+func (s *State) writeTuples(ctx context.Context, tuples []relationTuple) error {
+	for _, t := range tuples {
+		if err := s.writeTuple(ctx, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *State) deleteTuples(ctx context.Context, tuples []relationTuple) error {
+	for _, t := range tuples {
+		if err := s.deleteTuple(ctx, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *State) writeTuple(ctx context.Context, t relationTuple) error {
+	if s.ketoClient != nil {
+		if t.subjectSet.relation != "" {
+			return s.ketoClient.CreateSubjectSetTuple(ctx, t.namespace, t.object, t.relation, ketoSubjectSet(t.subjectSet))
+		}
+		return s.ketoClient.CreateRelationTuple(ctx, t.namespace, t.object, t.relation, t.subject)
+	} else if s.openfgaClient != nil {
+		return s.openfgaClient.CreateRelationTuple(ctx, openfgaObject(t.namespace, t.object), t.relation, openfgaUser(t))
+	}
+	return nil
+}
+
+func (s *State) deleteTuple(ctx context.Context, t relationTuple) error {
+	if s.ketoClient != nil {
+		if t.subjectSet.relation != "" {
+			return s.ketoClient.DeleteSubjectSetTuple(ctx, t.namespace, t.object, t.relation, ketoSubjectSet(t.subjectSet))
+		}
+		return s.ketoClient.DeleteRelationTuple(ctx, t.namespace, t.object, t.relation, t.subject)
+	} else if s.openfgaClient != nil {
+		return s.openfgaClient.DeleteRelationTuple(ctx, openfgaObject(t.namespace, t.object), t.relation, openfgaUser(t))
+	}
 	return nil
 }
+
+func ketoSubjectSet(set subjectSet) keto.SubjectSet {
+	return keto.SubjectSet{Namespace: set.namespace, Object: set.object, Relation: set.relation}
+}
+
+// openfgaUser returns the OpenFGA user of the tuple, <object>#<relation> for a subject set.
+func openfgaUser(t relationTuple) string {
+	if t.subjectSet.relation != "" {
+		return openfgaObject(t.subjectSet.namespace, t.subjectSet.object) + "#" + t.subjectSet.relation
+	}
+	return t.subject
+}
@@ -1,6 +1,12 @@
 package zanzibar
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -114,3 +120,218 @@ func TestKeto(t *testing.T) {
 		})
 	}
 }
+
+func TestRelationTuples(t *testing.T) {
+	policies := engine.Policies{
+		{
+			ID:      "pol-doc",
+			Members: engine.MakeSubjects("user:anne"),
+			Statements: engine.Statements{
+				{
+					Effect:    engine.EffectAllow,
+					Resources: engine.MakeResources("document:Z"),
+					Actions:   engine.MakeActions("reader"),
+					Role:      "editor",
+					Projects:  engine.MakeProjects("app"),
+				},
+			},
+		},
+	}
+	roles := engine.Roles{{ID: "editor", Actions: engine.MakeActions("writer")}}
+	bindings := engine.RoleBindings{{Subject: "user:kitty", Role: "group:admins"}}
+
+	tuples, err := relationTuples(policies, roles, bindings)
+	assert.Nil(t, err)
+	assert.Equal(t, []relationTuple{
+		{namespace: "app", object: "document:Z", relation: "reader", subject: "user:anne"},
+		{namespace: "app", object: "document:Z", relation: "writer", subject: "user:anne"},
+		{namespace: "", object: "group:admins", relation: MemberRelation, subject: "user:kitty"},
+	}, tuples)
+
+	// the members of a bound role are granted through the subject set of the role
+	policies[0].Members = engine.MakeSubjects("group:admins")
+	tuples, err = relationTuples(policies, roles, bindings)
+	assert.Nil(t, err)
+	assert.Equal(t, []relationTuple{
+		{namespace: "app", object: "document:Z", relation: "reader", subjectSet: subjectSet{namespace: "app", object: "group:admins", relation: MemberRelation}},
+		{namespace: "app", object: "document:Z", relation: "reader", subjectSet: subjectSet{object: "group:admins", relation: MemberRelation}},
+		{namespace: "app", object: "document:Z", relation: "writer", subjectSet: subjectSet{namespace: "app", object: "group:admins", relation: MemberRelation}},
+		{namespace: "app", object: "document:Z", relation: "writer", subjectSet: subjectSet{object: "group:admins", relation: MemberRelation}},
+		{namespace: "", object: "group:admins", relation: MemberRelation, subject: "user:kitty"},
+	}, tuples)
+
+	policies[0].Statements[0].Effect = engine.EffectDeny
+	_, err = relationTuples(policies, roles, bindings)
+	assert.ErrorIs(t, err, ErrDenyNotSupported)
+}
+
+// ketoServer serves the relation tuple and check endpoints of the Keto REST API used by the engine,
+// the subject sets are resolved by the checks.
+type ketoServer struct {
+	mu     sync.Mutex
+	tuples map[string]bool
+}
+
+type ketoTuple struct {
+	Namespace  string `json:"namespace"`
+	Object     string `json:"object"`
+	Relation   string `json:"relation"`
+	SubjectID  string `json:"subject_id,omitempty"`
+	SubjectSet *struct {
+		Namespace string `json:"namespace"`
+		Object    string `json:"object"`
+		Relation  string `json:"relation"`
+	} `json:"subject_set,omitempty"`
+}
+
+func (k *ketoServer) key(t ketoTuple) string {
+	if t.SubjectSet != nil {
+		return fmt.Sprintf("%s:%s#%s@(%s:%s#%s)", t.Namespace, t.Object, t.Relation, t.SubjectSet.Namespace, t.SubjectSet.Object, t.SubjectSet.Relation)
+	}
+	return fmt.Sprintf("%s:%s#%s@%s", t.Namespace, t.Object, t.Relation, t.SubjectID)
+}
+
+func (k *ketoServer) check(namespace, object, relation, subject string) bool {
+	if k.tuples[k.key(ketoTuple{Namespace: namespace, Object: object, Relation: relation, SubjectID: subject})] {
+		return true
+	}
+	prefix := fmt.Sprintf("%s:%s#%s@(", namespace, object, relation)
+	for key := range k.tuples {
+		if set, ok := strings.CutPrefix(key, prefix); ok {
+			ns, rest, _ := strings.Cut(strings.TrimSuffix(set, ")"), ":")
+			obj, rel, _ := strings.Cut(rest, "#")
+			if k.check(ns, obj, rel, subject) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (k *ketoServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPut && r.URL.Path == "/admin/relation-tuples":
+		var t ketoTuple
+		_ = json.NewDecoder(r.Body).Decode(&t)
+		k.tuples[k.key(t)] = true
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(t)
+	case r.Method == http.MethodDelete && r.URL.Path == "/admin/relation-tuples":
+		t := ketoTuple{Namespace: query.Get("namespace"), Object: query.Get("object"), Relation: query.Get("relation"), SubjectID: query.Get("subject_id")}
+		if query.Has("subject_set.relation") {
+			t.SubjectSet = &struct {
+				Namespace string `json:"namespace"`
+				Object    string `json:"object"`
+				Relation  string `json:"relation"`
+			}{query.Get("subject_set.namespace"), query.Get("subject_set.object"), query.Get("subject_set.relation")}
+		}
+		delete(k.tuples, k.key(t))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && r.URL.Path == "/relation-tuples/check/openapi":
+		allowed := k.check(query.Get("namespace"), query.Get("object"), query.Get("relation"), query.Get("subject_id"))
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]bool{"allowed": allowed})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestRoleBindings_Keto(t *testing.T) {
+	server := httptest.NewServer(&ketoServer{tuples: map[string]bool{}})
+	defer server.Close()
+
+	s, err := NewEngine(t.Context(), WithKeto(server.URL, server.URL, false))
+	assert.Nil(t, err)
+
+	isAuthorized := func(subject engine.Subject, project engine.Project) bool {
+		allowed, err := s.IsAuthorized(t.Context(), subject, "read", "doc1", project)
+		assert.Nil(t, err)
+		return allowed
+	}
+
+	policies := engine.MakePolicyMap(
+		engine.Policy{
+			ID:         "pol-editor",
+			Members:    engine.MakeSubjects("editor"),
+			Statements: engine.Statements{{Effect: engine.EffectAllow, Resources: engine.MakeResources("doc1"), Actions: engine.MakeActions("read"), Projects: engine.MakeProjects("app")}},
+		},
+		engine.Policy{
+			ID:         "pol-viewer",
+			Members:    engine.MakeSubjects("viewer"),
+			Statements: engine.Statements{{Effect: engine.EffectAllow, Resources: engine.MakeResources("doc1"), Actions: engine.MakeActions("read"), Projects: engine.MakeProjects("app")}},
+		},
+	)
+	roles := engine.MakeRoleMap(nil, engine.RoleBindings{
+		{Subject: "anne", Role: "editor", Project: "app"},
+		{Subject: "bob", Role: "editor"},
+	})
+	assert.Nil(t, s.SetPolicies(t.Context(), policies, roles))
+
+	assert.True(t, isAuthorized("anne", "app"))
+	assert.False(t, isAuthorized("anne", "other"))
+	// bound in every project
+	assert.True(t, isAuthorized("bob", "app"))
+	assert.False(t, isAuthorized("kitty", "app"))
+
+	// the policies of a role bound afterwards grant its members, not the role itself
+	assert.True(t, isAuthorized("viewer", "app"))
+	assert.Nil(t, s.AddRoleBindings(t.Context(), engine.RoleBindings{{Subject: "carol", Role: "viewer", Project: "app"}}))
+	assert.True(t, isAuthorized("carol", "app"))
+	assert.False(t, isAuthorized("viewer", "app"))
+
+	assert.Nil(t, s.RemoveRoleBindings(t.Context(), engine.RoleBindings{{Subject: "anne", Role: "editor", Project: "app"}}))
+	assert.False(t, isAuthorized("anne", "app"))
+	assert.True(t, isAuthorized("bob", "app"))
+
+	// the tuples of the previous policies are replaced
+	assert.Nil(t, s.SetPolicies(t.Context(), engine.MakePolicyMap(engine.Policy{
+		ID:         "pol-kitty",
+		Members:    engine.MakeSubjects("kitty"),
+		Statements: engine.Statements{{Effect: engine.EffectAllow, Resources: engine.MakeResources("doc1"), Actions: engine.MakeActions("read"), Projects: engine.MakeProjects("app")}},
+	}), nil))
+	assert.True(t, isAuthorized("kitty", "app"))
+	assert.False(t, isAuthorized("bob", "app"))
+	assert.False(t, isAuthorized("carol", "app"))
+}
+
+func TestAllProjects_Keto(t *testing.T) {
+	server := httptest.NewServer(&ketoServer{tuples: map[string]bool{}})
+	defer server.Close()
+
+	s, err := NewEngine(t.Context(), WithKeto(server.URL, server.URL, false))
+	assert.Nil(t, err)
+
+	policies := engine.MakePolicyMap(engine.Policy{
+		ID:         "pol-reader",
+		Members:    engine.MakeSubjects("anne"),
+		Statements: engine.Statements{{Effect: engine.EffectAllow, Resources: engine.MakeResources("doc1"), Actions: engine.MakeActions("read")}},
+	})
+	assert.Nil(t, s.SetPolicies(t.Context(), policies, nil))
+
+	// the statements without projects grant every project
+	for _, project := range engine.MakeProjects("", "app") {
+		allowed, err := s.IsAuthorized(t.Context(), "anne", "read", "doc1", project)
+		assert.Nil(t, err)
+		assert.True(t, allowed, project)
+	}
+
+	allowed, err := s.BatchIsAuthorized(t.Context(), engine.MakeRequests(
+		engine.MakeRequest("anne", "read", "doc1", "app"),
+		engine.MakeRequest("anne", "write", "doc1", "app"),
+		engine.MakeRequest("bob", "read", "doc1", "app"),
+	))
+	assert.Nil(t, err)
+	assert.Equal(t, []bool{true, false, false}, allowed)
+}
+
+func TestOpenfgaObject(t *testing.T) {
+	assert.Equal(t, "document:Z", openfgaObject("", "document:Z"))
+	assert.Equal(t, "document:app/Z", openfgaObject("app", "document:Z"))
+	assert.Equal(t, "role:app/editor", openfgaObject("app", roleObject("editor")))
+	assert.Equal(t, "group:admins", roleObject("group:admins"))
+}
@@ -2,20 +2,17 @@ package middleware
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-kratos/kratos/v2/log"
 	"github.com/go-kratos/kratos/v2/middleware"
 
 	"github.com/tx7do/kratos-authz/engine"
+	"github.com/tx7do/kratos-authz/engine/audit"
 )
 
 func Server(authorizer engine.Authorizer, opts ...Option) middleware.Middleware {
-	o := &options{
-		log: log.NewHelper(log.With(log.DefaultLogger, "module", "authz.middleware")),
-	}
-	for _, opt := range opts {
-		opt(o)
-	}
+	o := newOptions(opts...)
 
 	if authorizer == nil {
 		return nil
@@ -23,60 +20,118 @@ func Server(authorizer engine.Authorizer, opts ...Option) middleware.Middleware
 
 	return func(handler middleware.Handler) middleware.Handler {
 		return func(ctx context.Context, req interface{}) (interface{}, error) {
-			var (
-				allowed bool
-				err     error
-			)
-
-			claims, ok := engine.AuthClaimsFromContext(ctx)
-			if !ok {
-				o.log.Error("authz middleware: missing auth claims in context")
-				return nil, ErrMissingClaims
+			ctx, err := o.authorize(ctx, authorizer, req)
+			if err != nil {
+				return nil, err
 			}
+			return handler(ctx, req)
+		}
+	}
+}
 
-			if claims.Action == nil || claims.Resource == nil {
-				o.log.Error("authz middleware: missing auth claims in context")
-				return nil, ErrInvalidClaims
-			}
+func newOptions(opts ...Option) *options {
+	o := &options{
+		log:            log.NewHelper(log.With(log.DefaultLogger, "module", "authz.middleware")),
+		maxConcurrency: DefaultMaxConcurrency,
+		errorEncoder:   DefaultErrorEncoder,
+		claimsMaxAge:   DefaultClaimsMaxAge,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
 
-			var project engine.Project
-			if claims.Project == nil {
-				project = ""
-			} else {
-				project = *claims.Project
-			}
+// authorize checks the request in ctx, it is shared by the middleware, the gRPC interceptors
+// and the net/http handler. The returned context is the one to hand to the next handler.
+func (o *options) authorize(ctx context.Context, authorizer engine.Authorizer, req interface{}) (context.Context, error) {
+	ctx = NewDecisionIDContext(ctx, newDecisionID())
 
-			if claims.Subject != nil {
-				allowed, err = authorizer.IsAuthorized(ctx, *claims.Subject, *claims.Action, *claims.Resource, project)
-				if err != nil {
-					o.log.Errorf("authz middleware: authorization failed for subject %s, action %s, resource %s, project %s: %v",
-						*claims.Subject, *claims.Action, *claims.Resource, project, err)
-					return nil, err
-				}
-				if !allowed {
-					return nil, ErrUnauthorized
-				}
-			} else if claims.Subjects != nil && len(*claims.Subjects) > 0 {
-				for _, subject := range *claims.Subjects {
-					allowed, err = authorizer.IsAuthorized(ctx, engine.Subject(subject), *claims.Action, *claims.Resource, project)
-					if err != nil {
-						o.log.Errorf("authz middleware: authorization failed for subject %s, action %s, resource %s, project %s: %v",
-							subject, *claims.Action, *claims.Resource, project, err)
-						return nil, err
-					}
-					if allowed {
-						break
-					}
-				}
-				if !allowed {
-					return nil, ErrUnauthorized
-				}
-			} else {
-				o.log.Error("authz middleware: missing subject in auth claims")
-				return nil, ErrMissingSubject
-			}
+	if o.skipper.skip(ctx) {
+		o.auditSkipped(ctx)
+		return ctx, nil
+	}
 
-			return handler(ctx, req)
+	var err error
+	if o.projectScoped {
+		ctx, err = o.authorizeProjects(ctx, authorizer, req)
+	} else {
+		err = o.authorizeProject(ctx, authorizer, req)
+	}
+	if err != nil {
+		if o.monitored(ctx, err) {
+			return ctx, nil
 		}
+		return ctx, o.encodeError(ctx, err)
+	}
+
+	return ctx, nil
+}
+
+// authorizeProject checks the request for the single project of the claims.
+func (o *options) authorizeProject(ctx context.Context, authorizer engine.Authorizer, req interface{}) error {
+
+	var allowed bool
+
+	claims, ok := engine.AuthClaimsFromContext(ctx)
+	if !ok {
+		o.log.Error("authz middleware: missing auth claims in context")
+		return ErrMissingClaims
+	}
+
+	action, resource, err := o.resolve(ctx, req, claims)
+	if err != nil {
+		o.log.Errorf("authz middleware: unable to resolve action and resource: %v", err)
+		return err
+	}
+
+	var project engine.Project
+	if claims.Project == nil {
+		project = ""
+	} else {
+		project = *claims.Project
+	}
+
+	if claims.Subject != nil {
+		allowed, err = o.isAuthorized(ctx, authorizer, *claims.Subject, action, resource, project)
+		if err != nil {
+			o.log.Errorf("authz middleware: authorization failed for subject %s, action %s, resource %s, project %s: %v",
+				*claims.Subject, action, resource, project, err)
+			return err
+		}
+		if !allowed {
+			return ErrUnauthorized
+		}
+	} else if claims.Subjects != nil && len(*claims.Subjects) > 0 {
+		return o.checkSubjects(ctx, authorizer, claimsSubjects(claims), action, resource, project)
+	} else {
+		o.log.Error("authz middleware: missing subject in auth claims")
+		return ErrMissingSubject
+	}
+
+	return nil
+}
+
+// isAuthorized checks a single subject, recording the check when an auditor is set.
+func (o *options) isAuthorized(ctx context.Context, authorizer engine.Authorizer, subject engine.Subject, action engine.Action, resource engine.Resource, project engine.Project) (bool, error) {
+	if o.auditor == nil {
+		return authorizer.IsAuthorized(ctx, subject, action, resource, project)
+	}
+
+	start := time.Now()
+	decision, err := authorizer.Decide(ctx, subject, action, resource, project)
+	o.audit(ctx, audit.NewRecord(ctx, engine.MakeRequest(subject, action, resource, project), decision, err, time.Since(start)))
+	if err != nil {
+		return false, err
+	}
+
+	return decision.Allowed(), nil
+}
+
+// audit records a check, tagged with the decision ID of the request.
+func (o *options) audit(ctx context.Context, record *audit.Record) {
+	record.ID, _ = DecisionIDFromContext(ctx)
+	if err := o.auditor.Audit(ctx, record); err != nil {
+		o.log.Errorf("authz middleware: failed to audit authorization check: %v", err)
 	}
 }
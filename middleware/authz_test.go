@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -8,6 +9,11 @@ import (
 	"testing"
 
 	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-authz/engine"
+	"github.com/tx7do/kratos-authz/engine/audit"
+	"github.com/tx7do/kratos-authz/engine/noop"
 )
 
 type myTransport struct {
@@ -437,3 +443,29 @@ func TestServer_OPAWithDomain(t *testing.T) {
 	//	})
 	//}
 }
+
+func TestServer_Audit(t *testing.T) {
+	authorizer, _ := noop.NewEngine(t.Context())
+	ring := audit.NewRingBuffer(10)
+
+	handler := Server(authorizer, WithAuditor(ring))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "reply", nil
+	})
+
+	subject := engine.Subject("bobo")
+	action := engine.Action("GET")
+	resource := engine.Resource("/api/users")
+	ctx := engine.ContextWithAuthClaims(t.Context(), &engine.AuthClaims{Subject: &subject, Action: &action, Resource: &resource})
+	ctx = transport.NewServerContext(ctx, &myTransport{operation: "/api.user.v1.UserService/ListUsers", endpoint: "http://127.0.0.1:8000"})
+
+	reply, err := handler(ctx, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "reply", reply)
+
+	records := ring.Records()
+	assert.Len(t, records, 1)
+	assert.Equal(t, subject, records[0].Subject)
+	assert.Equal(t, engine.EffectAllow, records[0].Effect)
+	assert.Equal(t, "/api.user.v1.UserService/ListUsers", records[0].Operation)
+	assert.Equal(t, "http://127.0.0.1:8000", records[0].Endpoint)
+}
@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 
 	"github.com/tx7do/kratos-authz/engine"
 )
@@ -13,3 +15,37 @@ func NewContext(ctx context.Context, claims *engine.AuthClaims) context.Context
 func FromContext(ctx context.Context) (*engine.AuthClaims, bool) {
 	return engine.AuthClaimsFromContext(ctx)
 }
+
+type projectsKey struct{}
+
+// NewProjectsContext stores the projects the request has been authorized for.
+func NewProjectsContext(ctx context.Context, projects engine.Projects) context.Context {
+	return context.WithValue(ctx, projectsKey{}, projects)
+}
+
+// ProjectsFromContext returns the projects the request has been authorized for with WithProjectScope,
+// so that the handlers can restrict their queries to them.
+func ProjectsFromContext(ctx context.Context) (engine.Projects, bool) {
+	projects, ok := ctx.Value(projectsKey{}).(engine.Projects)
+	return projects, ok
+}
+
+type decisionIDKey struct{}
+
+// NewDecisionIDContext stores the ID of the authorization of the request.
+func NewDecisionIDContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, decisionIDKey{}, id)
+}
+
+// DecisionIDFromContext returns the ID of the authorization of the request, it is the ID of its
+// audit records and is attached to the errors of the rejected requests.
+func DecisionIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(decisionIDKey{}).(string)
+	return id, ok
+}
+
+func newDecisionID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	stderrors "errors"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// DecisionIDMetadataKey is the metadata key of the decision ID in the errors of DefaultErrorEncoder
+// and StatusErrorEncoder.
+const DecisionIDMetadataKey = "decision_id"
+
+// DenialKind classifies the rejected requests.
+type DenialKind int
+
+const (
+	// DenialUnauthenticated is a request without claims or subject.
+	DenialUnauthenticated DenialKind = iota
+	// DenialForbidden is a request denied by the authorizer, or which could not be resolved.
+	DenialForbidden
+	// DenialStepUpRequired is a request requiring a stronger authentication, see ErrStepUpRequired.
+	DenialStepUpRequired
+	// DenialEngineFailure is a request the authorizer failed to check.
+	DenialEngineFailure
+)
+
+func (k DenialKind) String() string {
+	switch k {
+	case DenialUnauthenticated:
+		return "unauthenticated"
+	case DenialForbidden:
+		return "forbidden"
+	case DenialStepUpRequired:
+		return "step-up required"
+	case DenialEngineFailure:
+		return "engine failure"
+	}
+	return "unknown"
+}
+
+// Denial describes a rejected request to an ErrorEncoder.
+type Denial struct {
+	Kind DenialKind
+	// DecisionID is the ID of the audit records of the request.
+	DecisionID string
+	// Err is the error the request has been rejected with, as returned by the authorizer for engine failures.
+	Err error
+}
+
+// ErrorEncoder builds the error returned to the client for a rejected request.
+type ErrorEncoder func(ctx context.Context, denial *Denial) error
+
+// DefaultErrorEncoder returns the Kratos errors, such as the Forbidden errors of the middleware or
+// ErrStepUpRequired, as they are, and ErrEngineFailure for the failures of the authorizer.
+func DefaultErrorEncoder(_ context.Context, denial *Denial) error {
+	if denial.Kind == DenialEngineFailure {
+		return withDecisionID(ErrEngineFailure.WithCause(denial.Err), denial)
+	}
+
+	if se := new(errors.Error); stderrors.As(denial.Err, &se) {
+		return withDecisionID(se, denial)
+	}
+	return withDecisionID(ErrUnauthorized, denial)
+}
+
+// StatusErrorEncoder returns distinct statuses for the rejected requests: 401 when the claims or
+// the subject are missing, 403 when the request is denied, 401 with the STEP_UP_REQUIRED reason
+// when a stronger authentication is required, and 503 for the failures of the authorizer.
+func StatusErrorEncoder(ctx context.Context, denial *Denial) error {
+	if denial.Kind == DenialUnauthenticated {
+		return withDecisionID(ErrUnauthenticated, denial)
+	}
+	return DefaultErrorEncoder(ctx, denial)
+}
+
+func withDecisionID(err *errors.Error, denial *Denial) *errors.Error {
+	if denial.DecisionID == "" {
+		return err
+	}
+
+	md := make(map[string]string, len(err.Metadata)+1)
+	for k, v := range err.Metadata {
+		md[k] = v
+	}
+	md[DecisionIDMetadataKey] = denial.DecisionID
+	return err.WithMetadata(md)
+}
+
+// encodeError classifies err and builds the error returned to the client with the ErrorEncoder.
+func (o *options) encodeError(ctx context.Context, err error) error {
+	denial := &Denial{Kind: denialKind(err), Err: err}
+	denial.DecisionID, _ = DecisionIDFromContext(ctx)
+
+	return o.errorEncoder(ctx, denial)
+}
+
+func denialKind(err error) DenialKind {
+	// the Forbidden errors of the middleware all match each other with errors.Is
+	switch {
+	case err == ErrMissingClaims, err == ErrMissingSubject:
+		return DenialUnauthenticated
+	case errors.Reason(err) == reasonStepUpRequired:
+		return DenialStepUpRequired
+	}
+
+	if se := new(errors.Error); stderrors.As(err, &se) {
+		return DenialForbidden
+	}
+	return DenialEngineFailure
+}
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-authz/engine"
+	"github.com/tx7do/kratos-authz/engine/audit"
+	"github.com/tx7do/kratos-authz/engine/noop"
+)
+
+var errEvaluation = stderrors.New("error in query evaluation: rego_type_error: undefined ref data.internal")
+
+// errorAuthorizer fails every check with err.
+type errorAuthorizer struct {
+	engine.Authorizer
+	err error
+}
+
+func (a *errorAuthorizer) IsAuthorized(_ context.Context, _ engine.Subject, _ engine.Action, _ engine.Resource, _ engine.Project) (bool, error) {
+	return false, a.err
+}
+
+func (a *errorAuthorizer) Decide(_ context.Context, _ engine.Subject, _ engine.Action, _ engine.Resource, _ engine.Project) (*engine.Decision, error) {
+	return nil, a.err
+}
+
+func TestServer_ErrorEncoder(t *testing.T) {
+	noopAuthorizer, _ := noop.NewEngine(t.Context())
+	subject := engine.Subject("bobo")
+
+	cases := map[string]struct {
+		authorizer engine.Authorizer
+		claims     *engine.AuthClaims
+		opts       []Option
+		code       int
+		reason     string
+		cause      error
+	}{
+		"missing claims": {
+			authorizer: noopAuthorizer,
+			code:       403,
+			reason:     reason,
+		},
+		"missing claims status": {
+			authorizer: noopAuthorizer,
+			opts:       []Option{WithErrorEncoder(StatusErrorEncoder)},
+			code:       401,
+			reason:     reasonUnauthenticated,
+		},
+		"missing subject status": {
+			authorizer: noopAuthorizer,
+			claims:     &engine.AuthClaims{Action: &resourceAction, Resource: &resourceName},
+			opts:       []Option{WithErrorEncoder(StatusErrorEncoder)},
+			code:       401,
+			reason:     reasonUnauthenticated,
+		},
+		"unresolved claims status": {
+			authorizer: noopAuthorizer,
+			claims:     &engine.AuthClaims{Subject: &subject},
+			opts:       []Option{WithErrorEncoder(StatusErrorEncoder)},
+			code:       403,
+			reason:     reason,
+		},
+		"denied status": {
+			authorizer: &resourceAuthorizer{Authorizer: noopAuthorizer},
+			claims:     &engine.AuthClaims{Subject: &subject, Action: &resourceAction, Resource: &resourceName},
+			opts:       []Option{WithErrorEncoder(StatusErrorEncoder)},
+			code:       403,
+			reason:     reason,
+		},
+		"engine failure": {
+			authorizer: &errorAuthorizer{Authorizer: noopAuthorizer, err: errEvaluation},
+			claims:     &engine.AuthClaims{Subject: &subject, Action: &resourceAction, Resource: &resourceName},
+			code:       503,
+			reason:     reasonUnavailable,
+			cause:      errEvaluation,
+		},
+		"step-up required": {
+			authorizer: &errorAuthorizer{Authorizer: noopAuthorizer, err: ErrStepUpRequired.WithMetadata(map[string]string{"acr": "mfa"})},
+			claims:     &engine.AuthClaims{Subject: &subject, Action: &resourceAction, Resource: &resourceName},
+			opts:       []Option{WithErrorEncoder(StatusErrorEncoder)},
+			code:       401,
+			reason:     reasonStepUpRequired,
+		},
+	}
+
+	for descr, tc := range cases {
+		t.Run(descr, func(t *testing.T) {
+			ring := audit.NewRingBuffer(10)
+			handler := Server(tc.authorizer, append(tc.opts, WithAuditor(ring))...)(func(ctx context.Context, req interface{}) (interface{}, error) {
+				return "reply", nil
+			})
+
+			ctx := t.Context()
+			if tc.claims != nil {
+				ctx = engine.ContextWithAuthClaims(ctx, tc.claims)
+			}
+
+			_, err := handler(ctx, nil)
+			se := errors.FromError(err)
+			assert.EqualValues(t, tc.code, se.Code)
+			assert.Equal(t, tc.reason, se.Reason)
+			assert.NotEmpty(t, se.Metadata[DecisionIDMetadataKey])
+			if tc.reason == reasonStepUpRequired {
+				assert.Equal(t, "mfa", se.Metadata["acr"])
+			}
+			if tc.cause != nil {
+				assert.ErrorIs(t, err, tc.cause)
+				assert.NotContains(t, se.Message, "rego")
+			}
+
+			for _, r := range ring.Records() {
+				assert.Equal(t, se.Metadata[DecisionIDMetadataKey], r.ID)
+			}
+		})
+	}
+}
+
+func TestServer_CustomErrorEncoder(t *testing.T) {
+	noopAuthorizer, _ := noop.NewEngine(t.Context())
+	authorizer := &errorAuthorizer{Authorizer: noopAuthorizer, err: errEvaluation}
+
+	var denial *Denial
+	handler := Server(authorizer, WithErrorEncoder(func(_ context.Context, d *Denial) error {
+		denial = d
+		return errors.InternalServer("CUSTOM", "custom")
+	}))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "reply", nil
+	})
+
+	subject := engine.Subject("bobo")
+	ctx := engine.ContextWithAuthClaims(t.Context(), &engine.AuthClaims{Subject: &subject, Action: &resourceAction, Resource: &resourceName})
+
+	_, err := handler(ctx, nil)
+	assert.Equal(t, "CUSTOM", errors.Reason(err))
+	assert.Equal(t, DenialEngineFailure, denial.Kind)
+	assert.Equal(t, errEvaluation, denial.Err)
+	assert.NotEmpty(t, denial.DecisionID)
+}
@@ -1,9 +1,17 @@
 package middleware
 
-import "github.com/go-kratos/kratos/v2/errors"
+import (
+	stderrors "errors"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
 
 const (
 	reason string = "FORBIDDEN"
+
+	reasonUnauthenticated string = "UNAUTHENTICATED"
+	reasonStepUpRequired  string = "STEP_UP_REQUIRED"
+	reasonUnavailable     string = "AUTHZ_UNAVAILABLE"
 )
 
 var (
@@ -11,4 +19,21 @@ var (
 	ErrMissingClaims  = errors.Forbidden(reason, "missing authz claims")
 	ErrMissingSubject = errors.Forbidden(reason, "missing authz subject")
 	ErrInvalidClaims  = errors.Forbidden(reason, "invalid authz claims")
+
+	ErrUnresolvedRequest = errors.Forbidden(reason, "unable to resolve authz action and resource")
+
+	// ErrUnauthenticated is returned by StatusErrorEncoder for the requests without claims or subject.
+	ErrUnauthenticated = errors.Unauthorized(reasonUnauthenticated, "missing authz claims")
+	// ErrStepUpRequired can be returned by the authorizers and resolvers requiring a stronger
+	// authentication of the caller, the request is then rejected with it.
+	ErrStepUpRequired = errors.Unauthorized(reasonStepUpRequired, "step-up authentication required")
+	// ErrEngineFailure replaces the errors of the authorizers, which are kept as its cause but are
+	// not sent to the clients.
+	ErrEngineFailure = errors.ServiceUnavailable(reasonUnavailable, "authorization temporarily unavailable")
+)
+
+var (
+	errInvalidSignature  = stderrors.New("invalid claims signature")
+	errExpiredClaims     = stderrors.New("expired claims")
+	errMissingSigningKey = stderrors.New("no claims signing key")
 )
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/middleware"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+// FilterAuthorized returns the items the subjects are authorized for, in their original order.
+// The pair of every item is checked with a single FilterAuthorizedPairs call, the items sharing
+// a pair are sent once and kept or dropped together.
+func FilterAuthorized[T any](ctx context.Context, authorizer engine.Authorizer, subjects engine.Subjects, items []T, pair func(T) engine.Pair) ([]T, error) {
+	if len(items) == 0 {
+		return items, nil
+	}
+
+	pairs := make([]engine.Pair, len(items))
+	unique := make(engine.Pairs, 0, len(items))
+	seen := make(map[engine.Pair]struct{}, len(items))
+	for i, item := range items {
+		pairs[i] = pair(item)
+		if _, ok := seen[pairs[i]]; !ok {
+			seen[pairs[i]] = struct{}{}
+			unique = append(unique, pairs[i])
+		}
+	}
+
+	authorized, err := authorizer.FilterAuthorizedPairs(ctx, subjects, unique)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make(map[engine.Pair]struct{}, len(authorized))
+	for _, p := range authorized {
+		allowed[p] = struct{}{}
+	}
+
+	result := make([]T, 0, len(items))
+	for i, item := range items {
+		if _, ok := allowed[pairs[i]]; ok {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// FilterReply returns a middleware dropping the items of the replies of type R which the
+// subjects of the AuthClaims are not authorized for, see FilterAuthorized. The items are read
+// with get and written back with set, the replies of other types are left untouched.
+// Its errors are encoded like the ones of Server, with the encoder of WithErrorEncoder.
+func FilterReply[R any, T any](authorizer engine.Authorizer, get func(R) []T, set func(R, []T), pair func(T) engine.Pair, opts ...Option) middleware.Middleware {
+	o := newOptions(opts...)
+
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			reply, err := handler(ctx, req)
+			if err != nil {
+				return reply, err
+			}
+
+			r, ok := reply.(R)
+			if !ok {
+				return reply, nil
+			}
+
+			if _, ok = DecisionIDFromContext(ctx); !ok {
+				ctx = NewDecisionIDContext(ctx, newDecisionID())
+			}
+
+			claims, ok := engine.AuthClaimsFromContext(ctx)
+			if !ok {
+				o.log.Error("authz middleware: missing auth claims in context")
+				return nil, o.encodeError(ctx, ErrMissingClaims)
+			}
+			subjects := claimsSubjects(claims)
+			if len(subjects) == 0 {
+				o.log.Error("authz middleware: missing subject in auth claims")
+				return nil, o.encodeError(ctx, ErrMissingSubject)
+			}
+
+			items, err := FilterAuthorized(ctx, authorizer, subjects, get(r), pair)
+			if err != nil {
+				o.log.Errorf("authz middleware: failed to filter the reply for subjects %v: %v", subjects, err)
+				return nil, o.encodeError(ctx, err)
+			}
+			set(r, items)
+
+			return r, nil
+		}
+	}
+}
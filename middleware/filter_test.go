@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-authz/engine"
+	"github.com/tx7do/kratos-authz/engine/noop"
+)
+
+// pairsAuthorizer authorizes the pairs on its resources, returning them in reverse order.
+type pairsAuthorizer struct {
+	engine.Authorizer
+	resources map[engine.Resource]bool
+
+	err   error
+	calls int
+	pairs engine.Pairs
+}
+
+func (a *pairsAuthorizer) FilterAuthorizedPairs(_ context.Context, _ engine.Subjects, pairs engine.Pairs) (engine.Pairs, error) {
+	a.calls++
+	a.pairs = pairs
+	if a.err != nil {
+		return nil, a.err
+	}
+
+	result := engine.Pairs{}
+	for i := len(pairs) - 1; i >= 0; i-- {
+		if a.resources[pairs[i].Resource] {
+			result = append(result, pairs[i])
+		}
+	}
+	return result, nil
+}
+
+type item struct {
+	id   string
+	name string
+}
+
+func itemPair(i item) engine.Pair {
+	return engine.MakePair("items:"+i.id, "items:get")
+}
+
+func TestFilterAuthorized(t *testing.T) {
+	noopAuthorizer, _ := noop.NewEngine(t.Context())
+	authorizer := &pairsAuthorizer{Authorizer: noopAuthorizer, resources: map[engine.Resource]bool{"items:1": true, "items:3": true}}
+
+	items := []item{{"1", "a"}, {"2", "b"}, {"3", "c"}, {"1", "d"}, {"4", "e"}}
+	result, err := FilterAuthorized(t.Context(), authorizer, engine.MakeSubjects("bobo"), items, itemPair)
+	assert.NoError(t, err)
+	assert.Equal(t, []item{{"1", "a"}, {"3", "c"}, {"1", "d"}}, result)
+	assert.Equal(t, 1, authorizer.calls)
+	assert.Len(t, authorizer.pairs, 4)
+
+	result, err = FilterAuthorized(t.Context(), authorizer, engine.MakeSubjects("bobo"), []item{}, itemPair)
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+	assert.Equal(t, 1, authorizer.calls)
+}
+
+type listItemsReply struct {
+	items []item
+}
+
+func TestFilterReply(t *testing.T) {
+	noopAuthorizer, _ := noop.NewEngine(t.Context())
+	authorizer := &pairsAuthorizer{Authorizer: noopAuthorizer, resources: map[engine.Resource]bool{"items:2": true}}
+
+	filter := FilterReply(authorizer,
+		func(r *listItemsReply) []item { return r.items },
+		func(r *listItemsReply, items []item) { r.items = items },
+		itemPair,
+	)
+
+	handler := filter(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return req, nil
+	})
+
+	subject := engine.Subject("bobo")
+	ctx := engine.ContextWithAuthClaims(t.Context(), &engine.AuthClaims{Subject: &subject})
+
+	reply, err := handler(ctx, &listItemsReply{items: []item{{"1", "a"}, {"2", "b"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, &listItemsReply{items: []item{{"2", "b"}}}, reply)
+
+	reply, err = handler(ctx, "reply")
+	assert.NoError(t, err)
+	assert.Equal(t, "reply", reply)
+
+	_, err = handler(t.Context(), &listItemsReply{})
+	assert.ErrorIs(t, err, ErrMissingClaims)
+
+	// the errors are encoded like the ones of Server
+	authorizer.err = stderrors.New("connection refused by policy store")
+	_, err = handler(ctx, &listItemsReply{items: []item{{"1", "a"}}})
+	assert.ErrorIs(t, err, ErrEngineFailure)
+	assert.NotContains(t, errors.FromError(err).Message, "policy store")
+	assert.NotEmpty(t, errors.FromError(err).Metadata[DecisionIDMetadataKey])
+
+	handler = FilterReply(authorizer,
+		func(r *listItemsReply) []item { return r.items },
+		func(r *listItemsReply, items []item) { r.items = items },
+		itemPair,
+		WithErrorEncoder(StatusErrorEncoder),
+	)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return req, nil
+	})
+	_, err = handler(t.Context(), &listItemsReply{})
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
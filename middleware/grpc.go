@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/transport"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+// UnaryServerInterceptor authorizes the unary calls of a plain gRPC server, with the same options as Server.
+func UnaryServerInterceptor(authorizer engine.Authorizer, opts ...Option) grpc.UnaryServerInterceptor {
+	o := newOptions(opts...)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := o.authorize(grpcServerContext(ctx, info.FullMethod), authorizer, req)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor authorizes the streams of a plain gRPC server when they are opened, and
+// every received message as well with WithStreamMessageCheck. The request is nil when the stream
+// is opened, the message when it is received.
+func StreamServerInterceptor(authorizer engine.Authorizer, opts ...Option) grpc.StreamServerInterceptor {
+	o := newOptions(opts...)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := o.authorize(grpcServerContext(ss.Context(), info.FullMethod), authorizer, nil)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx, authorizer: authorizer, options: o})
+	}
+}
+
+// serverStream exposes the context carrying the transport, and authorizes the received messages.
+type serverStream struct {
+	grpc.ServerStream
+	ctx        context.Context
+	authorizer engine.Authorizer
+	options    *options
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if !s.options.streamMessageCheck {
+		return nil
+	}
+	_, err := s.options.authorize(s.ctx, s.authorizer, m)
+	return err
+}
+
+// grpcServerContext adds a server transport to the context of a call handled outside Kratos,
+// so that the operation is known to the skip options, the resolvers and the auditor.
+func grpcServerContext(ctx context.Context, fullMethod string) context.Context {
+	if _, ok := transport.FromServerContext(ctx); ok {
+		return ctx
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	return transport.NewServerContext(ctx, &grpcTransport{
+		operation:   fullMethod,
+		reqHeader:   headerCarrier(md.Copy()),
+		replyHeader: headerCarrier(metadata.MD{}),
+	})
+}
+
+type grpcTransport struct {
+	operation   string
+	reqHeader   headerCarrier
+	replyHeader headerCarrier
+}
+
+func (tr *grpcTransport) Kind() transport.Kind {
+	return transport.KindGRPC
+}
+
+func (tr *grpcTransport) Endpoint() string {
+	return ""
+}
+
+func (tr *grpcTransport) Operation() string {
+	return tr.operation
+}
+
+func (tr *grpcTransport) RequestHeader() transport.Header {
+	return tr.reqHeader
+}
+
+func (tr *grpcTransport) ReplyHeader() transport.Header {
+	return tr.replyHeader
+}
+
+type headerCarrier metadata.MD
+
+func (mc headerCarrier) Get(key string) string {
+	vals := metadata.MD(mc).Get(key)
+	if len(vals) > 0 {
+		return vals[0]
+	}
+	return ""
+}
+
+func (mc headerCarrier) Set(key string, value string) {
+	metadata.MD(mc).Set(key, value)
+}
+
+func (mc headerCarrier) Add(key string, value string) {
+	metadata.MD(mc).Append(key, value)
+}
+
+func (mc headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(mc))
+	for k := range metadata.MD(mc) {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (mc headerCarrier) Values(key string) []string {
+	return metadata.MD(mc).Get(key)
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/tx7do/kratos-authz/engine"
+	"github.com/tx7do/kratos-authz/engine/noop"
+)
+
+func grpcTestContext(t *testing.T) context.Context {
+	subject := engine.Subject("bobo")
+	return engine.ContextWithAuthClaims(t.Context(), &engine.AuthClaims{Subject: &subject})
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	noopAuthorizer, _ := noop.NewEngine(t.Context())
+	authorizer := &resourceAuthorizer{Authorizer: noopAuthorizer, resource: "/api.user.v1.UserService/GetUser"}
+
+	interceptor := UnaryServerInterceptor(authorizer, WithResolver(TransportResolver))
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "reply", nil
+	}
+
+	reply, err := interceptor(grpcTestContext(t), nil, &grpc.UnaryServerInfo{FullMethod: "/api.user.v1.UserService/GetUser"}, handler)
+	assert.Nil(t, err)
+	assert.Equal(t, "reply", reply)
+
+	_, err = interceptor(grpcTestContext(t), nil, &grpc.UnaryServerInfo{FullMethod: "/api.user.v1.UserService/DeleteUser"}, handler)
+	assert.True(t, errors.IsForbidden(err))
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	messages []string
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *testServerStream) RecvMsg(m interface{}) error {
+	*(m.(*string)) = s.messages[0]
+	s.messages = s.messages[1:]
+	return nil
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	noopAuthorizer, _ := noop.NewEngine(t.Context())
+	authorizer := &resourceAuthorizer{Authorizer: noopAuthorizer, resource: "/api.chat.v1.ChatService/Chat"}
+
+	// the resource of the messages is their content
+	resolver := func(ctx context.Context, req interface{}) (engine.Action, engine.Resource, error) {
+		if m, ok := req.(*string); ok {
+			return GRPCAction, engine.Resource(*m), nil
+		}
+		return TransportResolver(ctx, req)
+	}
+
+	info := &grpc.StreamServerInfo{FullMethod: "/api.chat.v1.ChatService/Chat"}
+	receiveAll := func(srv interface{}, ss grpc.ServerStream) error {
+		for range 2 {
+			var m string
+			if err := ss.RecvMsg(&m); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	newStream := func() *testServerStream {
+		return &testServerStream{ctx: grpcTestContext(t), messages: []string{"/api.chat.v1.ChatService/Chat", "forbidden"}}
+	}
+
+	interceptor := StreamServerInterceptor(authorizer, WithResolver(resolver))
+	assert.Nil(t, interceptor(nil, newStream(), info, receiveAll))
+
+	interceptor = StreamServerInterceptor(authorizer, WithResolver(resolver), WithStreamMessageCheck(true))
+	assert.True(t, errors.IsForbidden(interceptor(nil, newStream(), info, receiveAll)))
+
+	err := interceptor(nil, newStream(), &grpc.StreamServerInfo{FullMethod: "/api.chat.v1.ChatService/Other"}, receiveAll)
+	assert.True(t, errors.IsForbidden(err))
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/transport"
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+// Handler authorizes the requests of a plain net/http server before passing them to next, with
+// the same options as Server. The operation of a request is its URL path, and the failures are
+// written with the Kratos error encoder.
+func Handler(authorizer engine.Authorizer, next http.Handler, opts ...Option) http.Handler {
+	o := newOptions(opts...)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if _, ok := transport.FromServerContext(ctx); !ok {
+			ctx = transport.NewServerContext(ctx, &httpTransport{request: r, replyHeader: w.Header()})
+			r = r.WithContext(ctx)
+		}
+
+		authorized, err := o.authorize(ctx, authorizer, r)
+		if err != nil {
+			khttp.DefaultErrorEncoder(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(authorized))
+	})
+}
+
+var _ khttp.Transporter = (*httpTransport)(nil)
+
+type httpTransport struct {
+	request     *http.Request
+	replyHeader http.Header
+}
+
+func (tr *httpTransport) Kind() transport.Kind {
+	return transport.KindHTTP
+}
+
+func (tr *httpTransport) Endpoint() string {
+	return ""
+}
+
+func (tr *httpTransport) Operation() string {
+	return tr.request.URL.Path
+}
+
+func (tr *httpTransport) RequestHeader() transport.Header {
+	return headerCarrierHTTP(tr.request.Header)
+}
+
+func (tr *httpTransport) ReplyHeader() transport.Header {
+	return headerCarrierHTTP(tr.replyHeader)
+}
+
+func (tr *httpTransport) Request() *http.Request {
+	return tr.request
+}
+
+// PathTemplate returns the path of the http.ServeMux pattern matched by the request (if any),
+// without its method and host, e.g. "/users/{id}" for "GET /users/{id}".
+func (tr *httpTransport) PathTemplate() string {
+	if i := strings.Index(tr.request.Pattern, "/"); i >= 0 {
+		return tr.request.Pattern[i:]
+	}
+	return ""
+}
+
+type headerCarrierHTTP http.Header
+
+func (hc headerCarrierHTTP) Get(key string) string {
+	return http.Header(hc).Get(key)
+}
+
+func (hc headerCarrierHTTP) Set(key string, value string) {
+	http.Header(hc).Set(key, value)
+}
+
+func (hc headerCarrierHTTP) Add(key string, value string) {
+	http.Header(hc).Add(key, value)
+}
+
+func (hc headerCarrierHTTP) Keys() []string {
+	keys := make([]string, 0, len(hc))
+	for k := range http.Header(hc) {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (hc headerCarrierHTTP) Values(key string) []string {
+	return http.Header(hc).Values(key)
+}
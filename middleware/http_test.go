@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-authz/engine"
+	"github.com/tx7do/kratos-authz/engine/noop"
+)
+
+func TestHandler(t *testing.T) {
+	noopAuthorizer, _ := noop.NewEngine(t.Context())
+	authorizer := &resourceAuthorizer{Authorizer: noopAuthorizer, resource: "/api/users/{id}"}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("reply"))
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /api/users/{id}", Handler(authorizer, ok, WithResolver(TransportResolver)))
+	mux.Handle("GET /api/orders/{id}", Handler(authorizer, ok, WithResolver(TransportResolver)))
+	mux.Handle("GET /healthz", Handler(authorizer, ok, WithResolver(TransportResolver), WithSkipPaths("/healthz")))
+
+	// the claims are set by an upstream handler
+	subject := engine.Subject("bobo")
+	server := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r.WithContext(engine.ContextWithAuthClaims(r.Context(), &engine.AuthClaims{Subject: &subject})))
+	})
+
+	for path, code := range map[string]int{
+		"/api/users/1":  http.StatusOK,
+		"/api/orders/1": http.StatusForbidden,
+		"/healthz":      http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, code, w.Code, path)
+	}
+}
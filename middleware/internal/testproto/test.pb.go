@@ -0,0 +1,326 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: testproto/test.proto
+
+package testproto
+
+import (
+	_ "github.com/tx7do/kratos-authz/api/authz/v1"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type User struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Org           string                 `protobuf:"bytes,2,opt,name=org,proto3" json:"org,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *User) Reset() {
+	*x = User{}
+	mi := &file_testproto_test_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *User) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*User) ProtoMessage() {}
+
+func (x *User) ProtoReflect() protoreflect.Message {
+	mi := &file_testproto_test_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use User.ProtoReflect.Descriptor instead.
+func (*User) Descriptor() ([]byte, []int) {
+	return file_testproto_test_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *User) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *User) GetOrg() string {
+	if x != nil {
+		return x.Org
+	}
+	return ""
+}
+
+type GetUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUserRequest) Reset() {
+	*x = GetUserRequest{}
+	mi := &file_testproto_test_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUserRequest) ProtoMessage() {}
+
+func (x *GetUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_testproto_test_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUserRequest.ProtoReflect.Descriptor instead.
+func (*GetUserRequest) Descriptor() ([]byte, []int) {
+	return file_testproto_test_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetUserRequest) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type UpdateUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          *User                  `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateUserRequest) Reset() {
+	*x = UpdateUserRequest{}
+	mi := &file_testproto_test_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateUserRequest) ProtoMessage() {}
+
+func (x *UpdateUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_testproto_test_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateUserRequest.ProtoReflect.Descriptor instead.
+func (*UpdateUserRequest) Descriptor() ([]byte, []int) {
+	return file_testproto_test_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *UpdateUserRequest) GetUser() *User {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+type ListUsersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUsersRequest) Reset() {
+	*x = ListUsersRequest{}
+	mi := &file_testproto_test_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUsersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUsersRequest) ProtoMessage() {}
+
+func (x *ListUsersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_testproto_test_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUsersRequest.ProtoReflect.Descriptor instead.
+func (*ListUsersRequest) Descriptor() ([]byte, []int) {
+	return file_testproto_test_proto_rawDescGZIP(), []int{3}
+}
+
+type ListUsersReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Users         []*User                `protobuf:"bytes,1,rep,name=users,proto3" json:"users,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUsersReply) Reset() {
+	*x = ListUsersReply{}
+	mi := &file_testproto_test_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUsersReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUsersReply) ProtoMessage() {}
+
+func (x *ListUsersReply) ProtoReflect() protoreflect.Message {
+	mi := &file_testproto_test_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUsersReply.ProtoReflect.Descriptor instead.
+func (*ListUsersReply) Descriptor() ([]byte, []int) {
+	return file_testproto_test_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListUsersReply) GetUsers() []*User {
+	if x != nil {
+		return x.Users
+	}
+	return nil
+}
+
+var File_testproto_test_proto protoreflect.FileDescriptor
+
+const file_testproto_test_proto_rawDesc = "" +
+	"\n" +
+	"\x14testproto/test.proto\x12\rauthz.test.v1\x1a\x14authz/v1/authz.proto\"(\n" +
+	"\x04User\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x10\n" +
+	"\x03org\x18\x02 \x01(\tR\x03org\" \n" +
+	"\x0eGetUserRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\"<\n" +
+	"\x11UpdateUserRequest\x12'\n" +
+	"\x04user\x18\x01 \x01(\v2\x13.authz.test.v1.UserR\x04user\"\x12\n" +
+	"\x10ListUsersRequest\";\n" +
+	"\x0eListUsersReply\x12)\n" +
+	"\x05users\x18\x01 \x03(\v2\x13.authz.test.v1.UserR\x05users2\xc1\x02\n" +
+	"\vUserService\x12b\n" +
+	"\aGetUser\x12\x1d.authz.test.v1.GetUserRequest\x1a\x13.authz.test.v1.User\"#\xa2\xbb\x18\x1f\n" +
+	"\riam:users:get\x12\x0eiam:users:{id}\x12\x80\x01\n" +
+	"\n" +
+	"UpdateUser\x12 .authz.test.v1.UpdateUserRequest\x1a\x13.authz.test.v1.User\";\xa2\xbb\x187\n" +
+	"\x10iam:users:update\x12#iam:orgs:{user.org}:users:{user.id}\x12K\n" +
+	"\tListUsers\x12\x1f.authz.test.v1.ListUsersRequest\x1a\x1d.authz.test.v1.ListUsersReplyBGZEgithub.com/tx7do/kratos-authz/middleware/internal/testproto;testprotob\x06proto3"
+
+var (
+	file_testproto_test_proto_rawDescOnce sync.Once
+	file_testproto_test_proto_rawDescData []byte
+)
+
+func file_testproto_test_proto_rawDescGZIP() []byte {
+	file_testproto_test_proto_rawDescOnce.Do(func() {
+		file_testproto_test_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_testproto_test_proto_rawDesc), len(file_testproto_test_proto_rawDesc)))
+	})
+	return file_testproto_test_proto_rawDescData
+}
+
+var file_testproto_test_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_testproto_test_proto_goTypes = []any{
+	(*User)(nil),              // 0: authz.test.v1.User
+	(*GetUserRequest)(nil),    // 1: authz.test.v1.GetUserRequest
+	(*UpdateUserRequest)(nil), // 2: authz.test.v1.UpdateUserRequest
+	(*ListUsersRequest)(nil),  // 3: authz.test.v1.ListUsersRequest
+	(*ListUsersReply)(nil),    // 4: authz.test.v1.ListUsersReply
+}
+var file_testproto_test_proto_depIdxs = []int32{
+	0, // 0: authz.test.v1.UpdateUserRequest.user:type_name -> authz.test.v1.User
+	0, // 1: authz.test.v1.ListUsersReply.users:type_name -> authz.test.v1.User
+	1, // 2: authz.test.v1.UserService.GetUser:input_type -> authz.test.v1.GetUserRequest
+	2, // 3: authz.test.v1.UserService.UpdateUser:input_type -> authz.test.v1.UpdateUserRequest
+	3, // 4: authz.test.v1.UserService.ListUsers:input_type -> authz.test.v1.ListUsersRequest
+	0, // 5: authz.test.v1.UserService.GetUser:output_type -> authz.test.v1.User
+	0, // 6: authz.test.v1.UserService.UpdateUser:output_type -> authz.test.v1.User
+	4, // 7: authz.test.v1.UserService.ListUsers:output_type -> authz.test.v1.ListUsersReply
+	5, // [5:8] is the sub-list for method output_type
+	2, // [2:5] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_testproto_test_proto_init() }
+func file_testproto_test_proto_init() {
+	if File_testproto_test_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_testproto_test_proto_rawDesc), len(file_testproto_test_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_testproto_test_proto_goTypes,
+		DependencyIndexes: file_testproto_test_proto_depIdxs,
+		MessageInfos:      file_testproto_test_proto_msgTypes,
+	}.Build()
+	File_testproto_test_proto = out.File
+	file_testproto_test_proto_goTypes = nil
+	file_testproto_test_proto_depIdxs = nil
+}
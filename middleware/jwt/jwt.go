@@ -0,0 +1,145 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	kjwt "github.com/go-kratos/kratos/v2/middleware/auth/jwt"
+	jwtv5 "github.com/golang-jwt/jwt/v5"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+// Server maps the claims of the token verified by the Kratos jwt middleware onto the
+// engine.AuthClaims expected by the authz middleware, it must be placed between both.
+//
+// The subject, prefixed, becomes AuthClaims.Subject. When subjects claims are configured the
+// subject and their values become AuthClaims.Subjects instead, so that each of them is checked.
+// Action and resource claims set by a previous middleware are kept.
+func Server(opts ...Option) middleware.Middleware {
+	o := &options{
+		subject: claimMapping{path: DefaultSubjectClaim},
+		log:     log.NewHelper(log.With(log.DefaultLogger, "module", "authz.middleware.jwt")),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tokenClaims, ok := kjwt.FromContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+
+			values, err := claimsMap(tokenClaims)
+			if err != nil {
+				o.log.Errorf("authz jwt middleware: unable to read the token claims: %v", err)
+				return nil, err
+			}
+
+			var claims engine.AuthClaims
+			if parent, ok := engine.AuthClaimsFromContext(ctx); ok {
+				claims = *parent
+			}
+			o.apply(values, &claims)
+
+			return handler(engine.ContextWithAuthClaims(ctx, &claims), req)
+		}
+	}
+}
+
+func (o *options) apply(values map[string]interface{}, claims *engine.AuthClaims) {
+	var subjects []string
+	for _, s := range lookupStrings(values, o.subject.path, false) {
+		subjects = append(subjects, o.subject.prefix+s)
+	}
+	for _, mapping := range o.subjects {
+		for _, s := range lookupStrings(values, mapping.path, true) {
+			subjects = append(subjects, mapping.prefix+s)
+		}
+	}
+
+	switch {
+	case len(o.subjects) == 0 && len(subjects) > 0:
+		subject := engine.Subject(subjects[0])
+		claims.Subject = &subject
+	case len(subjects) > 0:
+		claims.Subject = nil
+		claims.Subjects = &subjects
+	}
+
+	if o.project == "" {
+		return
+	}
+	switch projects := lookupStrings(values, o.project, false); len(projects) {
+	case 0:
+	case 1:
+		project := engine.Project(projects[0])
+		claims.Project = &project
+		claims.Projects = nil
+	default:
+		// the project of a previous middleware would be checked instead of the token ones
+		claims.Project = nil
+		claims.Projects = &projects
+	}
+}
+
+// claimsMap returns the token claims as a map, converting the custom claim structs through JSON.
+func claimsMap(claims jwtv5.Claims) (map[string]interface{}, error) {
+	if m, ok := claims.(jwtv5.MapClaims); ok {
+		return m, nil
+	}
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+	if err = json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// lookupStrings returns the string values at the dot separated path, space separated strings are split when split is set.
+func lookupStrings(values map[string]interface{}, path string, split bool) []string {
+	var value interface{} = values
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if value, ok = m[key]; !ok {
+			return nil
+		}
+	}
+
+	switch t := value.(type) {
+	case string:
+		if split {
+			return strings.Fields(t)
+		}
+		if t == "" {
+			return nil
+		}
+		return []string{t}
+	case []string:
+		return t
+	case []interface{}:
+		result := make([]string, 0, len(t))
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+
+	return nil
+}
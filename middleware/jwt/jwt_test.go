@@ -0,0 +1,112 @@
+package jwt
+
+import (
+	"context"
+	"testing"
+
+	kjwt "github.com/go-kratos/kratos/v2/middleware/auth/jwt"
+	jwtv5 "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+type customClaims struct {
+	jwtv5.RegisteredClaims
+	Tenant string `json:"tenant"`
+}
+
+func TestServer(t *testing.T) {
+	mapClaims := jwtv5.MapClaims{
+		"sub":    "alice",
+		"tenant": "project1",
+		"scope":  "read write",
+		"realm_access": map[string]interface{}{
+			"roles": []interface{}{"admin", "viewer"},
+		},
+		"groups":  []interface{}{"devs"},
+		"tenants": []interface{}{"project1", "project2"},
+	}
+
+	tests := []struct {
+		name     string
+		options  []Option
+		claims   jwtv5.Claims
+		parent   *engine.Project
+		subject  *engine.Subject
+		subjects *[]string
+		project  *engine.Project
+		projects *[]string
+	}{
+		{
+			name:    "subject",
+			options: []Option{WithSubjectClaim("sub", "user:local:")},
+			claims:  mapClaims,
+			subject: ptr(engine.Subject("user:local:alice")),
+		},
+		{
+			name: "subjects",
+			options: []Option{
+				WithSubjectClaim("sub", "user:local:"),
+				WithSubjectsClaim("realm_access.roles", "role:"),
+				WithSubjectsClaim("groups", "team:local:"),
+				WithSubjectsClaim("scope", "scope:"),
+				WithProjectClaim("tenant"),
+			},
+			claims:   mapClaims,
+			subjects: &[]string{"user:local:alice", "role:admin", "role:viewer", "team:local:devs", "scope:read", "scope:write"},
+			project:  ptr(engine.Project("project1")),
+		},
+		{
+			name:    "custom claims",
+			options: []Option{WithProjectClaim("tenant")},
+			claims:  &customClaims{RegisteredClaims: jwtv5.RegisteredClaims{Subject: "bobo"}, Tenant: "project2"},
+			subject: ptr(engine.Subject("bobo")),
+			project: ptr(engine.Project("project2")),
+		},
+		{
+			name:     "projects replace the parent project",
+			options:  []Option{WithProjectClaim("tenants")},
+			claims:   mapClaims,
+			parent:   ptr(engine.Project("project3")),
+			subject:  ptr(engine.Subject("alice")),
+			projects: &[]string{"project1", "project2"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			action := engine.Action("GET")
+			ctx := engine.ContextWithAuthClaims(t.Context(), &engine.AuthClaims{Action: &action, Project: test.parent})
+			ctx = kjwt.NewContext(ctx, test.claims)
+
+			var claims *engine.AuthClaims
+			_, err := Server(test.options...)(func(ctx context.Context, req interface{}) (interface{}, error) {
+				claims, _ = engine.AuthClaimsFromContext(ctx)
+				return nil, nil
+			})(ctx, nil)
+			require.NoError(t, err)
+			require.NotNil(t, claims)
+
+			assert.Equal(t, test.subject, claims.Subject)
+			assert.Equal(t, test.subjects, claims.Subjects)
+			assert.Equal(t, test.project, claims.Project)
+			assert.Equal(t, test.projects, claims.Projects)
+			assert.Equal(t, &action, claims.Action)
+		})
+	}
+}
+
+func TestServer_WithoutToken(t *testing.T) {
+	_, err := Server()(func(ctx context.Context, req interface{}) (interface{}, error) {
+		_, ok := engine.AuthClaimsFromContext(ctx)
+		assert.False(t, ok)
+		return nil, nil
+	})(t.Context(), nil)
+	assert.NoError(t, err)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
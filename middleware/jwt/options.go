@@ -0,0 +1,55 @@
+package jwt
+
+import (
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+const (
+	DefaultSubjectClaim = "sub"
+)
+
+type Option func(*options)
+
+// claimMapping maps the values found at a claim path onto subjects, with a prefix.
+type claimMapping struct {
+	path   string
+	prefix string
+}
+
+type options struct {
+	subject  claimMapping
+	subjects []claimMapping
+	project  string
+
+	log *log.Helper
+}
+
+// WithSubjectClaim sets the claim path of the subject, and the prefix added to it, e.g. "user:local:".
+// Nested claims are separated by dots, e.g. "user.id".
+func WithSubjectClaim(path, prefix string) Option {
+	return func(o *options) {
+		o.subject = claimMapping{path: path, prefix: prefix}
+	}
+}
+
+// WithSubjectsClaim adds the values of a claim to the subjects, e.g. the "roles" claim with the
+// "role:" prefix, or the "realm_access.roles" one. Space separated strings are split, as for the
+// "scope" claim.
+func WithSubjectsClaim(path, prefix string) Option {
+	return func(o *options) {
+		o.subjects = append(o.subjects, claimMapping{path: path, prefix: prefix})
+	}
+}
+
+// WithProjectClaim sets the claim path of the project (or tenant). A list of projects is set as AuthClaims.Projects.
+func WithProjectClaim(path string) Option {
+	return func(o *options) {
+		o.project = path
+	}
+}
+
+func WithLogger(logger log.Logger) Option {
+	return func(o *options) {
+		o.log = log.NewHelper(log.With(logger, "module", "authz.middleware.jwt"))
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// Monitor switches the middleware to the monitor-only mode at runtime: the requests are checked
+// and the denied ones are logged, audited and counted, but they are let through. Only the denials
+// of the policies are let through, the requests without claims or subject and the failures of the
+// engine are still rejected. The mode applies
+// to every operation, or to the operations matching a set of prefixes such as "/api.user.v1.UserService/".
+type Monitor struct {
+	all      atomic.Bool
+	prefixes atomic.Pointer[[]string]
+
+	denied atomic.Uint64
+}
+
+// NewMonitor creates a Monitor, which enforces every operation until it is enabled.
+func NewMonitor() *Monitor {
+	return &Monitor{}
+}
+
+// SetEnabled sets whether every operation is monitored only.
+func (m *Monitor) SetEnabled(enabled bool) {
+	m.all.Store(enabled)
+}
+
+// SetOperationPrefixes replaces the prefixes of the operations which are monitored only.
+func (m *Monitor) SetOperationPrefixes(prefixes ...string) {
+	m.prefixes.Store(&prefixes)
+}
+
+// Enabled reports whether the operation is monitored only.
+func (m *Monitor) Enabled(operation string) bool {
+	if m.all.Load() {
+		return true
+	}
+
+	if prefixes := m.prefixes.Load(); prefixes != nil {
+		for _, prefix := range *prefixes {
+			if strings.HasPrefix(operation, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Denied returns the number of denied requests which have been let through.
+func (m *Monitor) Denied() uint64 {
+	return m.denied.Load()
+}
+
+// monitored reports whether the rejected request in ctx must be let through, counting it if so.
+func (o *options) monitored(ctx context.Context, err error) bool {
+	// the Forbidden errors of the middleware all match each other with errors.Is
+	if o.monitor == nil || err != ErrUnauthorized {
+		return false
+	}
+
+	var operation string
+	if tr, ok := transport.FromServerContext(ctx); ok {
+		operation = tr.Operation()
+	}
+	if !o.monitor.Enabled(operation) {
+		return false
+	}
+
+	o.monitor.denied.Add(1)
+	id, _ := DecisionIDFromContext(ctx)
+	o.log.Warnf("authz middleware: monitor mode, letting denied request through, operation %s, decision %s: %v", operation, id, err)
+
+	return true
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-authz/engine"
+	"github.com/tx7do/kratos-authz/engine/audit"
+	"github.com/tx7do/kratos-authz/engine/noop"
+)
+
+func TestServer_Monitor(t *testing.T) {
+	noopAuthorizer, _ := noop.NewEngine(t.Context())
+	authorizer := &resourceAuthorizer{Authorizer: noopAuthorizer, resource: "/api/users"}
+	monitor := NewMonitor()
+	ring := audit.NewRingBuffer(10)
+
+	handler := Server(authorizer, WithMonitor(monitor), WithAuditor(ring))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "reply", nil
+	})
+
+	call := func(operation string, resource engine.Resource) error {
+		subject := engine.Subject("bobo")
+		ctx := engine.ContextWithAuthClaims(t.Context(), &engine.AuthClaims{Subject: &subject, Action: &resourceAction, Resource: &resource})
+		ctx = transport.NewServerContext(ctx, &myTransport{operation: operation})
+		_, err := handler(ctx, nil)
+		return err
+	}
+
+	assert.ErrorIs(t, call("/api.user.v1.UserService/DeleteUser", "/api/users/1"), ErrUnauthorized)
+
+	monitor.SetOperationPrefixes("/api.user.v1.UserService/")
+	assert.NoError(t, call("/api.user.v1.UserService/DeleteUser", "/api/users/1"))
+	assert.NoError(t, call("/api.user.v1.UserService/ListUsers", "/api/users"))
+	assert.ErrorIs(t, call("/api.order.v1.OrderService/DeleteOrder", "/api/orders/1"), ErrUnauthorized)
+	assert.EqualValues(t, 1, monitor.Denied())
+
+	monitor.SetEnabled(true)
+	assert.NoError(t, call("/api.order.v1.OrderService/DeleteOrder", "/api/orders/1"))
+	assert.EqualValues(t, 2, monitor.Denied())
+
+	// the requests without subject and the engine failures are still rejected
+	ctx := engine.ContextWithAuthClaims(t.Context(), &engine.AuthClaims{Action: &resourceAction, Resource: &resourceName})
+	_, err := handler(ctx, nil)
+	assert.Error(t, err)
+
+	failing := Server(&subjectAuthorizer{Authorizer: noopAuthorizer, subjects: map[engine.Subject]bool{"broken": false}}, WithMonitor(monitor))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "reply", nil
+	})
+	subjects := []string{"broken"}
+	_, err = failing(engine.ContextWithAuthClaims(t.Context(), &engine.AuthClaims{Subjects: &subjects, Action: &resourceAction, Resource: &resourceName}), nil)
+	assert.ErrorIs(t, err, ErrEngineFailure)
+	assert.EqualValues(t, 2, monitor.Denied())
+
+	monitor.SetEnabled(false)
+	monitor.SetOperationPrefixes()
+	assert.ErrorIs(t, call("/api.user.v1.UserService/DeleteUser", "/api/users/1"), ErrUnauthorized)
+	assert.EqualValues(t, 2, monitor.Denied())
+
+	// the denied requests are audited whether they are let through or not
+	denied := 0
+	for _, r := range ring.Records() {
+		if !r.Allowed() {
+			denied++
+		}
+	}
+	assert.Equal(t, 5, denied)
+}
@@ -1,13 +1,39 @@
 package middleware
 
 import (
+	"regexp"
+	"time"
+
 	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/kratos-authz/engine/audit"
 )
 
 type Option func(*options)
 
 type options struct {
-	log *log.Helper
+	log     *log.Helper
+	auditor audit.Auditor
+
+	resolver           Resolver
+	operationResolvers map[string]Resolver
+
+	skipper skipper
+
+	streamMessageCheck bool
+
+	subjectsMode   SubjectsMode
+	errorPolicy    ErrorPolicy
+	maxConcurrency int
+
+	projectScoped bool
+
+	errorEncoder ErrorEncoder
+
+	monitor *Monitor
+
+	claimsKey    []byte
+	claimsMaxAge time.Duration
 }
 
 func WithLogger(logger log.Logger) Option {
@@ -15,3 +41,135 @@ func WithLogger(logger log.Logger) Option {
 		o.log = log.NewHelper(log.With(logger, "module", "authz.middleware"))
 	}
 }
+
+// WithAuditor records every authorization check of the middleware.
+// The checks are then done with Decide, so that the matched policies are recorded.
+func WithAuditor(auditor audit.Auditor) Option {
+	return func(o *options) {
+		o.auditor = auditor
+	}
+}
+
+// WithResolver derives the action and resource of the requests whose claims do not carry them,
+// see TransportResolver.
+func WithResolver(resolver Resolver) Option {
+	return func(o *options) {
+		o.resolver = resolver
+	}
+}
+
+// WithOperationResolver derives the action and resource of the requests to the operation,
+// taking precedence over WithResolver.
+func WithOperationResolver(operation string, resolver Resolver) Option {
+	return func(o *options) {
+		if o.operationResolvers == nil {
+			o.operationResolvers = map[string]Resolver{}
+		}
+		o.operationResolvers[operation] = resolver
+	}
+}
+
+// WithSkipOperationPrefixes skips the authorization of the operations starting with one of the prefixes,
+// e.g. "/grpc.health.v1.Health/".
+func WithSkipOperationPrefixes(prefixes ...string) Option {
+	return func(o *options) {
+		o.skipper.prefixes = append(o.skipper.prefixes, prefixes...)
+	}
+}
+
+// WithSkipOperationRegexps skips the authorization of the operations matching one of the expressions.
+func WithSkipOperationRegexps(regexps ...*regexp.Regexp) Option {
+	return func(o *options) {
+		o.skipper.regexps = append(o.skipper.regexps, regexps...)
+	}
+}
+
+// WithSkipPaths skips the authorization of the HTTP requests whose path matches one of the
+// patterns, in the path.Match syntax, e.g. "/public/*".
+func WithSkipPaths(patterns ...string) Option {
+	return func(o *options) {
+		o.skipper.paths = append(o.skipper.paths, patterns...)
+	}
+}
+
+// WithRequireOperations only authorizes the listed operations, every other one is skipped.
+func WithRequireOperations(operations ...string) Option {
+	return func(o *options) {
+		o.skipper.required = append(o.skipper.required, operations...)
+	}
+}
+
+// WithStreamMessageCheck makes StreamServerInterceptor authorize every received message,
+// not only the opening of the stream.
+func WithStreamMessageCheck(check bool) Option {
+	return func(o *options) {
+		o.streamMessageCheck = check
+	}
+}
+
+// WithSubjectsMode sets how the AuthClaims.Subjects are checked, AnyOf by default.
+func WithSubjectsMode(mode SubjectsMode) Option {
+	return func(o *options) {
+		o.subjectsMode = mode
+	}
+}
+
+// WithErrorPolicy sets how the failing subject checks are handled, FailClosed by default.
+func WithErrorPolicy(policy ErrorPolicy) Option {
+	return func(o *options) {
+		o.errorPolicy = policy
+	}
+}
+
+// WithMaxConcurrency bounds the subjects checked concurrently.
+func WithMaxConcurrency(n int) Option {
+	return func(o *options) {
+		o.maxConcurrency = n
+	}
+}
+
+// WithProjectScope authorizes the requests for a set of projects instead of a single one: the
+// AuthClaims.Projects are checked with ProjectsAuthorized, or every project of the subjects is
+// looked up with FilterAuthorizedProjects when none are requested. The request is rejected when
+// no project is authorized, otherwise the authorized projects are available to the handlers
+// through ProjectsFromContext.
+func WithProjectScope() Option {
+	return func(o *options) {
+		o.projectScoped = true
+	}
+}
+
+// WithErrorEncoder sets the function building the errors returned for the rejected requests,
+// DefaultErrorEncoder by default.
+func WithErrorEncoder(encoder ErrorEncoder) Option {
+	return func(o *options) {
+		o.errorEncoder = encoder
+	}
+}
+
+// WithMonitor lets the denied requests through for the operations monitored only by the Monitor,
+// which can be switched at runtime.
+func WithMonitor(monitor *Monitor) Option {
+	return func(o *options) {
+		o.monitor = monitor
+	}
+}
+
+// WithClaimsSigningKey signs the claims forwarded by Client with HMAC-SHA256, RestoreClaims then
+// rejects the claims without a valid signature. Both need the key, the claims are neither forwarded
+// nor restored without it.
+func WithClaimsSigningKey(key []byte) Option {
+	return func(o *options) {
+		o.claimsKey = key
+	}
+}
+
+// WithClaimsMaxAge makes RestoreClaims reject the claims forwarded for longer than maxAge,
+// DefaultClaimsMaxAge by default. A non-positive maxAge keeps the default.
+func WithClaimsMaxAge(maxAge time.Duration) Option {
+	return func(o *options) {
+		if maxAge > 0 {
+			o.claimsMaxAge = maxAge
+		}
+	}
+}
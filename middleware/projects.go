@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/tx7do/kratos-authz/engine"
+	"github.com/tx7do/kratos-authz/engine/audit"
+)
+
+// authorizeProjects checks the request for the projects of the claims, or for every project the
+// subjects can access when none is requested, and stores the authorized ones in the context.
+// The action and resource of the request are checked in either case.
+func (o *options) authorizeProjects(ctx context.Context, authorizer engine.Authorizer, req interface{}) (context.Context, error) {
+	claims, ok := engine.AuthClaimsFromContext(ctx)
+	if !ok {
+		o.log.Error("authz middleware: missing auth claims in context")
+		return ctx, ErrMissingClaims
+	}
+
+	subjects := claimsSubjects(claims)
+	if len(subjects) == 0 {
+		o.log.Error("authz middleware: missing subject in auth claims")
+		return ctx, ErrMissingSubject
+	}
+
+	var requested engine.Projects
+	if claims.Projects != nil {
+		for _, project := range *claims.Projects {
+			requested = append(requested, engine.Project(project))
+		}
+	} else if claims.Project != nil {
+		requested = engine.Projects{*claims.Project}
+	}
+
+	action, resource, err := o.resolve(ctx, req, claims)
+	if err != nil {
+		o.log.Errorf("authz middleware: unable to resolve action and resource: %v", err)
+		return ctx, err
+	}
+
+	start := time.Now()
+	candidates := requested
+	if len(candidates) == 0 {
+		candidates, err = authorizer.FilterAuthorizedProjects(ctx, subjects)
+	}
+
+	var projects engine.Projects
+	if err == nil && len(candidates) > 0 {
+		projects, err = authorizer.ProjectsAuthorized(ctx, subjects, action, resource, candidates)
+	}
+
+	if o.auditor != nil {
+		var decision *engine.Decision
+		if err == nil {
+			if len(projects) > 0 {
+				decision = engine.MakeAllowDecision(authorizer.Name())
+			} else {
+				decision = engine.MakeDenyDecision(authorizer.Name(), "no project authorized")
+			}
+		}
+		record := audit.NewRecord(ctx, engine.MakeRequest("", action, resource, ""), decision, err, time.Since(start))
+		record.Subjects = subjects
+		o.audit(ctx, record)
+	}
+
+	if err != nil {
+		o.log.Errorf("authz middleware: authorization failed for subjects %v, action %s, resource %s, projects %v: %v",
+			subjects, action, resource, requested, err)
+		return ctx, err
+	}
+	if len(projects) == 0 {
+		return ctx, ErrUnauthorized
+	}
+
+	return NewProjectsContext(ctx, projects), nil
+}
+
+// claimsSubjects returns the subject of the claims, or its subjects when the subject is not set.
+func claimsSubjects(claims *engine.AuthClaims) engine.Subjects {
+	if claims.Subject != nil {
+		return engine.Subjects{*claims.Subject}
+	}
+	if claims.Subjects == nil {
+		return nil
+	}
+
+	subjects := make(engine.Subjects, 0, len(*claims.Subjects))
+	for _, subject := range *claims.Subjects {
+		subjects = append(subjects, engine.Subject(subject))
+	}
+	return subjects
+}
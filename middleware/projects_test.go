@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-authz/engine"
+	"github.com/tx7do/kratos-authz/engine/noop"
+)
+
+// projectsAuthorizer authorizes every subject for its own projects, and for its own actions when
+// they are set.
+type projectsAuthorizer struct {
+	engine.Authorizer
+	projects map[engine.Subject]engine.Projects
+	actions  map[engine.Subject]engine.Actions
+}
+
+func (a *projectsAuthorizer) ProjectsAuthorized(_ context.Context, subjects engine.Subjects, action engine.Action, _ engine.Resource, projects engine.Projects) (engine.Projects, error) {
+	result := engine.Projects{}
+	for _, project := range projects {
+		for _, subject := range subjects {
+			if actions, ok := a.actions[subject]; ok && !slices.Contains(actions, action) {
+				continue
+			}
+			if slices.Contains(a.projects[subject], project) {
+				result = append(result, project)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+func (a *projectsAuthorizer) FilterAuthorizedProjects(_ context.Context, subjects engine.Subjects) (engine.Projects, error) {
+	result := engine.Projects{}
+	for _, subject := range subjects {
+		result = append(result, a.projects[subject]...)
+	}
+	return result, nil
+}
+
+func TestServer_ProjectScope(t *testing.T) {
+	noopAuthorizer, _ := noop.NewEngine(t.Context())
+	authorizer := &projectsAuthorizer{
+		Authorizer: noopAuthorizer,
+		projects: map[engine.Subject]engine.Projects{
+			"bobo":  engine.MakeProjects("project1", "project2"),
+			"alice": engine.MakeProjects("project3"),
+			"carol": engine.MakeProjects("project4"),
+		},
+		actions: map[engine.Subject]engine.Actions{
+			"carol": engine.MakeActions("DELETE"),
+		},
+	}
+
+	handler := Server(authorizer, WithProjectScope())(func(ctx context.Context, req interface{}) (interface{}, error) {
+		projects, _ := ProjectsFromContext(ctx)
+		return projects, nil
+	})
+
+	cases := map[string]struct {
+		subject  engine.Subject
+		subjects []string
+		projects []string
+		project  engine.Project
+		expected engine.Projects
+		err      error
+	}{
+		"requested projects":           {subject: "bobo", projects: []string{"project2", "project3"}, expected: engine.MakeProjects("project2")},
+		"requested project":            {subject: "bobo", project: "project1", expected: engine.MakeProjects("project1")},
+		"every project":                {subject: "bobo", expected: engine.MakeProjects("project1", "project2")},
+		"every project of subjects":    {subjects: []string{"bobo", "alice"}, expected: engine.MakeProjects("project1", "project2", "project3")},
+		"no requested project":         {subject: "alice", projects: []string{"project1"}, err: ErrUnauthorized},
+		"no project":                   {subject: "eve", err: ErrUnauthorized},
+		"every project, no action":     {subject: "carol", err: ErrUnauthorized},
+		"requested project, no action": {subject: "carol", project: "project4", err: ErrUnauthorized},
+		"missing subject":              {err: ErrMissingSubject},
+	}
+
+	for descr, tc := range cases {
+		t.Run(descr, func(t *testing.T) {
+			claims := &engine.AuthClaims{Action: &resourceAction, Resource: &resourceName}
+			if tc.subject != "" {
+				claims.Subject = &tc.subject
+			}
+			if tc.subjects != nil {
+				claims.Subjects = &tc.subjects
+			}
+			if tc.projects != nil {
+				claims.Projects = &tc.projects
+			}
+			if tc.project != "" {
+				claims.Project = &tc.project
+			}
+
+			reply, err := handler(engine.ContextWithAuthClaims(t.Context(), claims), nil)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, reply)
+		})
+	}
+}
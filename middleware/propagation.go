@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+const (
+	// ClaimsHeader carries the forwarded AuthClaims.
+	ClaimsHeader = "x-authz-claims"
+	// ClaimsSignatureHeader carries the HMAC signature of the ClaimsHeader value.
+	ClaimsSignatureHeader = "x-authz-claims-signature"
+)
+
+// DefaultClaimsMaxAge is the time the forwarded claims are accepted by RestoreClaims for.
+const DefaultClaimsMaxAge = 5 * time.Minute
+
+// forwardedClaims are the parts of the AuthClaims identifying the caller, the action and the
+// resource are resolved again by every service.
+type forwardedClaims struct {
+	Subject  *engine.Subject `json:"sub,omitempty"`
+	Subjects *[]string       `json:"subs,omitempty"`
+	Project  *engine.Project `json:"proj,omitempty"`
+	Projects *[]string       `json:"projs,omitempty"`
+	IssuedAt int64           `json:"iat"`
+}
+
+// Client returns a client middleware forwarding the subjects and projects of the AuthClaims in
+// the context to the called service, in the ClaimsHeader of the request. The header is signed
+// with WithClaimsSigningKey, nothing is forwarded without the key.
+func Client(opts ...Option) middleware.Middleware {
+	o := newOptions(opts...)
+	if o.claimsKey == nil {
+		o.log.Error("authz middleware: no claims signing key, the auth claims are not forwarded")
+	}
+
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if o.claimsKey == nil {
+				return handler(ctx, req)
+			}
+
+			claims, ok := engine.AuthClaimsFromContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+
+			tr, ok := transport.FromClientContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+
+			value, err := encodeClaims(claims, time.Now())
+			if err != nil {
+				o.log.Errorf("authz middleware: failed to encode auth claims: %v", err)
+				return nil, err
+			}
+
+			tr.RequestHeader().Set(ClaimsHeader, value)
+			tr.RequestHeader().Set(ClaimsSignatureHeader, signClaims(o.claimsKey, value))
+
+			return handler(ctx, req)
+		}
+	}
+}
+
+// RestoreClaims returns a server middleware restoring the AuthClaims forwarded by Client, to be
+// placed before Server. The claims must be signed with the key of WithClaimsSigningKey and issued
+// within WithClaimsMaxAge, the other ones are rejected with ErrInvalidClaims. The claims already in
+// the context, authenticated by a previous middleware, are never replaced, and the requests without
+// forwarded claims are left untouched.
+func RestoreClaims(opts ...Option) middleware.Middleware {
+	o := newOptions(opts...)
+	if o.claimsKey == nil {
+		o.log.Error("authz middleware: no claims signing key, the forwarded auth claims are rejected")
+	}
+
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if _, ok := engine.AuthClaimsFromContext(ctx); ok {
+				return handler(ctx, req)
+			}
+
+			tr, ok := transport.FromServerContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+
+			value := tr.RequestHeader().Get(ClaimsHeader)
+			if value == "" {
+				return handler(ctx, req)
+			}
+
+			claims, err := o.restoreClaims(value, tr.RequestHeader().Get(ClaimsSignatureHeader))
+			if err != nil {
+				o.log.Errorf("authz middleware: rejected forwarded auth claims: %v", err)
+				return nil, ErrInvalidClaims
+			}
+
+			return handler(engine.ContextWithAuthClaims(ctx, claims), req)
+		}
+	}
+}
+
+func (o *options) restoreClaims(value, signature string) (*engine.AuthClaims, error) {
+	if o.claimsKey == nil {
+		return nil, errMissingSigningKey
+	}
+	if !verifyClaims(o.claimsKey, value, signature) {
+		return nil, errInvalidSignature
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var forwarded forwardedClaims
+	if err = json.Unmarshal(raw, &forwarded); err != nil {
+		return nil, err
+	}
+
+	if time.Since(time.Unix(forwarded.IssuedAt, 0)) > o.claimsMaxAge {
+		return nil, errExpiredClaims
+	}
+
+	return &engine.AuthClaims{
+		Subject:  forwarded.Subject,
+		Subjects: forwarded.Subjects,
+		Project:  forwarded.Project,
+		Projects: forwarded.Projects,
+	}, nil
+}
+
+func encodeClaims(claims *engine.AuthClaims, issuedAt time.Time) (string, error) {
+	raw, err := json.Marshal(forwardedClaims{
+		Subject:  claims.Subject,
+		Subjects: claims.Subjects,
+		Project:  claims.Project,
+		Projects: claims.Projects,
+		IssuedAt: issuedAt.Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func signClaims(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func verifyClaims(key []byte, value, signature string) bool {
+	expected, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hmac.Equal(mac.Sum(nil), expected)
+}
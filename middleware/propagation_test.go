@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+// forward calls a client chain with claims, and restores its request header on a server chain.
+func forward(t *testing.T, claims *engine.AuthClaims, client, server []Option) (*engine.AuthClaims, error) {
+	header := headerCarrier(metadata.MD{})
+
+	ctx := t.Context()
+	if claims != nil {
+		ctx = engine.ContextWithAuthClaims(ctx, claims)
+	}
+	ctx = transport.NewClientContext(ctx, &myTransport{reqHeader: header})
+	_, err := Client(client...)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})(ctx, nil)
+	assert.NoError(t, err)
+
+	ctx = transport.NewServerContext(t.Context(), &myTransport{reqHeader: header})
+	reply, err := RestoreClaims(server...)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		claims, _ := engine.AuthClaimsFromContext(ctx)
+		return claims, nil
+	})(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return reply.(*engine.AuthClaims), nil
+}
+
+func TestClaimsPropagation(t *testing.T) {
+	subject := engine.Subject("bobo")
+	project := engine.Project("project1")
+	subjects := []string{"user:bobo", "team:admins"}
+	projects := []string{"project1", "project2"}
+	action := engine.Action("GET")
+	key := []Option{WithClaimsSigningKey([]byte("secret"))}
+
+	restored, err := forward(t, &engine.AuthClaims{Subject: &subject, Project: &project, Action: &action}, key, key)
+	assert.NoError(t, err)
+	assert.Equal(t, &engine.AuthClaims{Subject: &subject, Project: &project}, restored)
+
+	restored, err = forward(t, &engine.AuthClaims{Subjects: &subjects, Projects: &projects}, key, key)
+	assert.NoError(t, err)
+	assert.Equal(t, &engine.AuthClaims{Subjects: &subjects, Projects: &projects}, restored)
+
+	restored, err = forward(t, nil, key, key)
+	assert.NoError(t, err)
+	assert.Nil(t, restored)
+}
+
+func TestClaimsPropagation_Signed(t *testing.T) {
+	subject := engine.Subject("bobo")
+	claims := &engine.AuthClaims{Subject: &subject}
+	key := WithClaimsSigningKey([]byte("secret"))
+
+	restored, err := forward(t, claims, []Option{key}, []Option{key})
+	assert.NoError(t, err)
+	assert.Equal(t, claims, restored)
+
+	// nothing is forwarded without key
+	restored, err = forward(t, claims, nil, []Option{key})
+	assert.NoError(t, err)
+	assert.Nil(t, restored)
+
+	// nothing is restored without key
+	_, err = forward(t, claims, []Option{key}, nil)
+	assert.ErrorIs(t, err, ErrInvalidClaims)
+
+	_, err = forward(t, claims, []Option{WithClaimsSigningKey([]byte("other"))}, []Option{key})
+	assert.ErrorIs(t, err, ErrInvalidClaims)
+
+	// tampered claims
+	o := newOptions(key)
+	value, _ := encodeClaims(claims, time.Now())
+	signature := signClaims(o.claimsKey, value)
+	tampered, _ := encodeClaims(&engine.AuthClaims{Subject: new(engine.Subject)}, time.Now())
+	_, err = o.restoreClaims(tampered, signature)
+	assert.ErrorIs(t, err, errInvalidSignature)
+	_, err = o.restoreClaims(value, signature[1:])
+	assert.ErrorIs(t, err, errInvalidSignature)
+
+	// expired claims
+	value, _ = encodeClaims(claims, time.Now().Add(-DefaultClaimsMaxAge-time.Second))
+	_, err = o.restoreClaims(value, signClaims(o.claimsKey, value))
+	assert.ErrorIs(t, err, errExpiredClaims)
+
+	o = newOptions(key, WithClaimsMaxAge(time.Hour))
+	_, err = o.restoreClaims(value, signClaims(o.claimsKey, value))
+	assert.NoError(t, err)
+
+	value, _ = encodeClaims(claims, time.Now().Add(-2*time.Hour))
+	_, err = o.restoreClaims(value, signClaims(o.claimsKey, value))
+	assert.ErrorIs(t, err, errExpiredClaims)
+}
+
+func TestClaimsPropagation_Authenticated(t *testing.T) {
+	key := WithClaimsSigningKey([]byte("secret"))
+	forged := engine.Subject("admin")
+	value, _ := encodeClaims(&engine.AuthClaims{Subject: &forged}, time.Now())
+
+	header := headerCarrier(metadata.MD{})
+	header.Set(ClaimsHeader, value)
+	header.Set(ClaimsSignatureHeader, signClaims([]byte("secret"), value))
+
+	subject := engine.Subject("bobo")
+	authenticated := &engine.AuthClaims{Subject: &subject}
+	ctx := engine.ContextWithAuthClaims(t.Context(), authenticated)
+	ctx = transport.NewServerContext(ctx, &myTransport{reqHeader: header})
+
+	reply, err := RestoreClaims(key)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		claims, _ := engine.AuthClaimsFromContext(ctx)
+		return claims, nil
+	})(ctx, nil)
+	assert.NoError(t, err)
+	assert.Same(t, authenticated, reply)
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/http"
+
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+// GRPCAction is the action resolved for gRPC operations.
+const GRPCAction engine.Action = "CALL"
+
+// Resolver derives the action and resource of a request, for the claims which do not carry them.
+type Resolver func(ctx context.Context, req interface{}) (engine.Action, engine.Resource, error)
+
+// TransportResolver derives the action and resource from the Kratos server transport:
+// for HTTP the method and the matched path template (e.g. "/api/users/{id}"), as expected
+// by the restfull_with_role Casbin model; for gRPC GRPCAction and the full operation name.
+func TransportResolver(ctx context.Context, _ interface{}) (engine.Action, engine.Resource, error) {
+	tr, ok := transport.FromServerContext(ctx)
+	if !ok {
+		return "", "", ErrUnresolvedRequest
+	}
+
+	switch tr.Kind() {
+	case transport.KindHTTP:
+		if ht, ok := tr.(http.Transporter); ok {
+			resource := ht.PathTemplate()
+			if resource == "" {
+				resource = ht.Request().URL.Path
+			}
+			return engine.Action(strings.ToUpper(ht.Request().Method)), engine.Resource(resource), nil
+		}
+	case transport.KindGRPC:
+		return GRPCAction, engine.Resource(tr.Operation()), nil
+	}
+
+	return "", "", ErrUnresolvedRequest
+}
+
+// resolve returns the action and resource of the claims, falling back on the resolvers for the missing ones.
+func (o *options) resolve(ctx context.Context, req interface{}, claims *engine.AuthClaims) (engine.Action, engine.Resource, error) {
+	if claims.Action != nil && claims.Resource != nil {
+		return *claims.Action, *claims.Resource, nil
+	}
+
+	resolver := o.resolver
+	if tr, ok := transport.FromServerContext(ctx); ok {
+		if r, ok := o.operationResolvers[tr.Operation()]; ok {
+			resolver = r
+		}
+	}
+	if resolver == nil {
+		return "", "", ErrInvalidClaims
+	}
+
+	action, resource, err := resolver(ctx, req)
+	if err != nil {
+		return "", "", err
+	}
+
+	if claims.Action != nil {
+		action = *claims.Action
+	}
+	if claims.Resource != nil {
+		resource = *claims.Resource
+	}
+
+	return action, resource, nil
+}
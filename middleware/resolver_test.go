@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-authz/engine"
+	"github.com/tx7do/kratos-authz/engine/noop"
+)
+
+type myHttpTransport struct {
+	myTransport
+	request      *http.Request
+	pathTemplate string
+}
+
+func (tr *myHttpTransport) Request() *http.Request {
+	return tr.request
+}
+
+func (tr *myHttpTransport) PathTemplate() string {
+	return tr.pathTemplate
+}
+
+func TestTransportResolver(t *testing.T) {
+	request, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/api/users/1", nil)
+
+	tests := []struct {
+		name      string
+		transport transport.Transporter
+		action    engine.Action
+		resource  engine.Resource
+		err       error
+	}{
+		{
+			name:      "http",
+			transport: &myHttpTransport{myTransport: myTransport{kind: transport.KindHTTP}, request: request, pathTemplate: "/api/users/{id}"},
+			action:    "GET",
+			resource:  "/api/users/{id}",
+		},
+		{
+			name:      "http without template",
+			transport: &myHttpTransport{myTransport: myTransport{kind: transport.KindHTTP}, request: request},
+			action:    "GET",
+			resource:  "/api/users/1",
+		},
+		{
+			name:      "grpc",
+			transport: &myTransport{kind: transport.KindGRPC, operation: "/api.user.v1.UserService/GetUser"},
+			action:    GRPCAction,
+			resource:  "/api.user.v1.UserService/GetUser",
+		},
+		{
+			name:      "unknown",
+			transport: &myTransport{kind: transport.KindHTTP},
+			err:       ErrUnresolvedRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			action, resource, err := TransportResolver(transport.NewServerContext(t.Context(), test.transport), nil)
+			assert.ErrorIs(t, err, test.err)
+			assert.Equal(t, test.action, action)
+			assert.Equal(t, test.resource, resource)
+		})
+	}
+}
+
+func TestServer_Resolver(t *testing.T) {
+	noopAuthorizer, _ := noop.NewEngine(t.Context())
+	authorizer := &resourceAuthorizer{Authorizer: noopAuthorizer, resource: "/api.user.v1.UserService/GetUser"}
+
+	handler := Server(authorizer,
+		WithResolver(TransportResolver),
+		WithOperationResolver("/api.user.v1.UserService/ListUsers", func(ctx context.Context, req interface{}) (engine.Action, engine.Resource, error) {
+			return "list", "/api.user.v1.UserService/GetUser", nil
+		}),
+	)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "reply", nil
+	})
+
+	subject := engine.Subject("bobo")
+	ctx := engine.ContextWithAuthClaims(t.Context(), &engine.AuthClaims{Subject: &subject})
+
+	for operation, allowed := range map[string]bool{
+		"/api.user.v1.UserService/GetUser":    true,
+		"/api.user.v1.UserService/ListUsers":  true,
+		"/api.user.v1.UserService/DeleteUser": false,
+	} {
+		_, err := handler(transport.NewServerContext(ctx, &myTransport{kind: transport.KindGRPC, operation: operation}), nil)
+		if allowed {
+			assert.Nil(t, err, operation)
+		} else {
+			assert.ErrorIs(t, err, ErrUnauthorized, operation)
+		}
+	}
+}
+
+// resourceAuthorizer only allows a single resource.
+type resourceAuthorizer struct {
+	engine.Authorizer
+	resource engine.Resource
+}
+
+func (a *resourceAuthorizer) IsAuthorized(_ context.Context, _ engine.Subject, _ engine.Action, resource engine.Resource, _ engine.Project) (bool, error) {
+	return resource == a.resource, nil
+}
+
+func (a *resourceAuthorizer) Decide(ctx context.Context, subject engine.Subject, action engine.Action, resource engine.Resource, project engine.Project) (*engine.Decision, error) {
+	if allowed, _ := a.IsAuthorized(ctx, subject, action, resource, project); allowed {
+		return engine.MakeAllowDecision("resource"), nil
+	}
+	return engine.MakeDenyDecision("resource", "denied"), nil
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/transport"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	authzV1 "github.com/tx7do/kratos-authz/api/authz/v1"
+	"github.com/tx7do/kratos-authz/engine"
+)
+
+var placeholderRegexp = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// rules caches the authz.v1.rule option of the operations, nil when the method has none.
+var rules sync.Map
+
+// ProtoRuleResolver derives the action and resource from the authz.v1.rule option of the
+// method of the operation, looked up in the global proto registry. The "{field}" placeholders
+// of the resource are replaced with the fields of the request message.
+func ProtoRuleResolver(ctx context.Context, req interface{}) (engine.Action, engine.Resource, error) {
+	tr, ok := transport.FromServerContext(ctx)
+	if !ok {
+		return "", "", ErrUnresolvedRequest
+	}
+
+	rule := operationRule(tr.Operation())
+	if rule == nil {
+		return "", "", ErrUnresolvedRequest
+	}
+
+	resource, err := interpolate(rule.GetResource(), req)
+	if err != nil {
+		return "", "", err
+	}
+
+	return engine.Action(rule.GetAction()), engine.Resource(resource), nil
+}
+
+// ChainResolvers returns the result of the first resolver which does not fail with ErrUnresolvedRequest
+// itself, e.g. ChainResolvers(ProtoRuleResolver, TransportResolver).
+func ChainResolvers(resolvers ...Resolver) Resolver {
+	return func(ctx context.Context, req interface{}) (engine.Action, engine.Resource, error) {
+		for _, resolver := range resolvers {
+			action, resource, err := resolver(ctx, req)
+			// kratos errors match on code and reason only, the wrapped resolution errors must stop the chain
+			if err != ErrUnresolvedRequest { //nolint:errorlint
+				return action, resource, err
+			}
+		}
+		return "", "", ErrUnresolvedRequest
+	}
+}
+
+// operationRule returns the rule of an operation such as "/api.user.v1.UserService/GetUser".
+func operationRule(operation string) *authzV1.Rule {
+	if cached, ok := rules.Load(operation); ok {
+		return cached.(*authzV1.Rule)
+	}
+
+	var rule *authzV1.Rule
+	name := strings.ReplaceAll(strings.TrimPrefix(operation, "/"), "/", ".")
+	if desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name)); err == nil {
+		if method, ok := desc.(protoreflect.MethodDescriptor); ok && method.Options() != nil {
+			if r, ok := proto.GetExtension(method.Options(), authzV1.E_Rule).(*authzV1.Rule); ok && r != nil {
+				rule = r
+			}
+		}
+	}
+
+	rules.Store(operation, rule)
+
+	return rule
+}
+
+// interpolate replaces the "{field}" placeholders of the template with the fields of the request message.
+func interpolate(template string, req interface{}) (string, error) {
+	if !strings.Contains(template, "{") {
+		return template, nil
+	}
+
+	message, ok := req.(proto.Message)
+	if !ok {
+		return "", fmt.Errorf("%w: request is not a proto message", ErrUnresolvedRequest)
+	}
+
+	var err error
+	result := placeholderRegexp.ReplaceAllStringFunc(template, func(placeholder string) string {
+		value, fieldErr := fieldValue(message.ProtoReflect(), placeholder[1:len(placeholder)-1])
+		if fieldErr != nil && err == nil {
+			err = fieldErr
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return result, nil
+}
+
+// fieldValue returns the string form of the scalar field at the dot separated path.
+func fieldValue(message protoreflect.Message, path string) (string, error) {
+	keys := strings.Split(path, ".")
+	for i, key := range keys {
+		field := message.Descriptor().Fields().ByName(protoreflect.Name(key))
+		if field == nil || field.IsList() || field.IsMap() {
+			return "", fmt.Errorf("%w: no field %q in %s", ErrUnresolvedRequest, path, message.Descriptor().FullName())
+		}
+
+		value := message.Get(field)
+		if i < len(keys)-1 {
+			if field.Kind() != protoreflect.MessageKind {
+				return "", fmt.Errorf("%w: field %q of %s is not a message", ErrUnresolvedRequest, key, message.Descriptor().FullName())
+			}
+			message = value.Message()
+			continue
+		}
+
+		switch field.Kind() {
+		case protoreflect.MessageKind, protoreflect.GroupKind:
+			return "", fmt.Errorf("%w: field %q of %s is not a scalar", ErrUnresolvedRequest, path, message.Descriptor().FullName())
+		case protoreflect.EnumKind:
+			if v := field.Enum().Values().ByNumber(value.Enum()); v != nil {
+				return string(v.Name()), nil
+			}
+		}
+		return value.String(), nil
+	}
+
+	return "", nil
+}
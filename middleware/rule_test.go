@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-authz/engine"
+	"github.com/tx7do/kratos-authz/middleware/internal/testproto"
+)
+
+func TestProtoRuleResolver(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		req       interface{}
+		action    engine.Action
+		resource  engine.Resource
+		err       bool
+	}{
+		{
+			name:      "field",
+			operation: "/authz.test.v1.UserService/GetUser",
+			req:       &testproto.GetUserRequest{Id: 42},
+			action:    "iam:users:get",
+			resource:  "iam:users:42",
+		},
+		{
+			name:      "nested fields",
+			operation: "/authz.test.v1.UserService/UpdateUser",
+			req:       &testproto.UpdateUserRequest{User: &testproto.User{Id: 7, Org: "acme"}},
+			action:    "iam:users:update",
+			resource:  "iam:orgs:acme:users:7",
+		},
+		{
+			name:      "unset nested message",
+			operation: "/authz.test.v1.UserService/UpdateUser",
+			req:       &testproto.UpdateUserRequest{},
+			action:    "iam:users:update",
+			resource:  "iam:orgs::users:0",
+		},
+		{
+			name:      "not a proto message",
+			operation: "/authz.test.v1.UserService/GetUser",
+			req:       "request",
+			err:       true,
+		},
+		{
+			name:      "method without rule",
+			operation: "/authz.test.v1.UserService/ListUsers",
+			req:       &testproto.ListUsersRequest{},
+			err:       true,
+		},
+		{
+			name:      "unknown method",
+			operation: "/authz.test.v1.UserService/Unknown",
+			err:       true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := transport.NewServerContext(t.Context(), &myTransport{kind: transport.KindGRPC, operation: test.operation})
+			action, resource, err := ProtoRuleResolver(ctx, test.req)
+			if test.err {
+				assert.ErrorIs(t, err, ErrUnresolvedRequest)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, test.action, action)
+			assert.Equal(t, test.resource, resource)
+		})
+	}
+}
+
+func TestChainResolvers(t *testing.T) {
+	resolver := ChainResolvers(ProtoRuleResolver, func(ctx context.Context, req interface{}) (engine.Action, engine.Resource, error) {
+		return "fallback", "fallback", nil
+	})
+
+	ctx := transport.NewServerContext(t.Context(), &myTransport{kind: transport.KindGRPC, operation: "/authz.test.v1.UserService/ListUsers"})
+	action, resource, err := resolver(ctx, &testproto.ListUsersRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, engine.Action("fallback"), action)
+	assert.Equal(t, engine.Resource("fallback"), resource)
+
+	// a rule which can not be interpolated does not fall back
+	ctx = transport.NewServerContext(t.Context(), &myTransport{kind: transport.KindGRPC, operation: "/authz.test.v1.UserService/GetUser"})
+	_, _, err = resolver(ctx, "request")
+	assert.NotNil(t, err)
+}
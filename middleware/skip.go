@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/http"
+
+	"github.com/tx7do/kratos-authz/engine"
+	"github.com/tx7do/kratos-authz/engine/audit"
+)
+
+// skipper selects the requests which are not authorized by the middleware.
+type skipper struct {
+	prefixes []string
+	regexps  []*regexp.Regexp
+	paths    []string
+	required []string
+}
+
+func (s *skipper) empty() bool {
+	return len(s.prefixes) == 0 && len(s.regexps) == 0 && len(s.paths) == 0 && len(s.required) == 0
+}
+
+// skip reports whether the request in ctx is excluded from the authorization.
+func (s *skipper) skip(ctx context.Context) bool {
+	if s.empty() {
+		return false
+	}
+
+	tr, ok := transport.FromServerContext(ctx)
+	if !ok {
+		return false
+	}
+	operation := tr.Operation()
+
+	if len(s.required) > 0 && !slices.Contains(s.required, operation) {
+		return true
+	}
+
+	for _, prefix := range s.prefixes {
+		if strings.HasPrefix(operation, prefix) {
+			return true
+		}
+	}
+
+	for _, re := range s.regexps {
+		if re.MatchString(operation) {
+			return true
+		}
+	}
+
+	if ht, ok := tr.(http.Transporter); ok && len(s.paths) > 0 {
+		urlPath := ht.Request().URL.Path
+		for _, pattern := range s.paths {
+			if matched, _ := path.Match(pattern, urlPath); matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// auditSkipped records a request which has not been authorized.
+func (o *options) auditSkipped(ctx context.Context) {
+	if o.auditor == nil {
+		return
+	}
+
+	var request engine.Request
+	if claims, ok := engine.AuthClaimsFromContext(ctx); ok {
+		if claims.Subject != nil {
+			request.Subject = *claims.Subject
+		}
+		if claims.Action != nil {
+			request.Action = *claims.Action
+		}
+		if claims.Resource != nil {
+			request.Resource = *claims.Resource
+		}
+		if claims.Project != nil {
+			request.Project = *claims.Project
+		}
+	}
+
+	record := audit.NewRecord(ctx, request, nil, nil, 0)
+	record.Effect = engine.EffectAllow
+	record.Skipped = true
+	record.Reason = "authorization skipped"
+
+	o.audit(ctx, record)
+}
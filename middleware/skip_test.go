@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-authz/engine"
+	"github.com/tx7do/kratos-authz/engine/audit"
+	"github.com/tx7do/kratos-authz/engine/noop"
+)
+
+func TestServer_Skip(t *testing.T) {
+	noopAuthorizer, _ := noop.NewEngine(t.Context())
+	// denies every request
+	authorizer := &resourceAuthorizer{Authorizer: noopAuthorizer}
+
+	subject := engine.Subject("bobo")
+	action := engine.Action("GET")
+	resource := engine.Resource("/api/users")
+	claims := &engine.AuthClaims{Subject: &subject, Action: &action, Resource: &resource}
+
+	public, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/public/index.html", nil)
+	private, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/api/users", nil)
+
+	tests := []struct {
+		name      string
+		options   []Option
+		transport transport.Transporter
+		skipped   bool
+	}{
+		{
+			name:      "operation prefix",
+			options:   []Option{WithSkipOperationPrefixes("/grpc.health.v1.Health/")},
+			transport: &myTransport{kind: transport.KindGRPC, operation: "/grpc.health.v1.Health/Check"},
+			skipped:   true,
+		},
+		{
+			name:      "operation prefix mismatch",
+			options:   []Option{WithSkipOperationPrefixes("/grpc.health.v1.Health/")},
+			transport: &myTransport{kind: transport.KindGRPC, operation: "/api.user.v1.UserService/GetUser"},
+		},
+		{
+			name:      "operation regexp",
+			options:   []Option{WithSkipOperationRegexps(regexp.MustCompile(`/Login$`))},
+			transport: &myTransport{kind: transport.KindGRPC, operation: "/api.auth.v1.AuthService/Login"},
+			skipped:   true,
+		},
+		{
+			name:      "http path",
+			options:   []Option{WithSkipPaths("/public/*")},
+			transport: &myHttpTransport{myTransport: myTransport{kind: transport.KindHTTP, operation: "/api.web.v1.Web/Static"}, request: public},
+			skipped:   true,
+		},
+		{
+			name:      "http path mismatch",
+			options:   []Option{WithSkipPaths("/public/*")},
+			transport: &myHttpTransport{myTransport: myTransport{kind: transport.KindHTTP, operation: "/api.user.v1.UserService/ListUsers"}, request: private},
+		},
+		{
+			name:      "not required",
+			options:   []Option{WithRequireOperations("/api.user.v1.UserService/DeleteUser")},
+			transport: &myTransport{kind: transport.KindGRPC, operation: "/api.user.v1.UserService/GetUser"},
+			skipped:   true,
+		},
+		{
+			name:      "required",
+			options:   []Option{WithRequireOperations("/api.user.v1.UserService/DeleteUser")},
+			transport: &myTransport{kind: transport.KindGRPC, operation: "/api.user.v1.UserService/DeleteUser"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ring := audit.NewRingBuffer(10)
+			handler := Server(authorizer, append(test.options, WithAuditor(ring))...)(func(ctx context.Context, req interface{}) (interface{}, error) {
+				return "reply", nil
+			})
+
+			ctx := transport.NewServerContext(engine.ContextWithAuthClaims(t.Context(), claims), test.transport)
+			_, err := handler(ctx, nil)
+
+			records := ring.Records()
+			assert.Len(t, records, 1)
+			if test.skipped {
+				assert.Nil(t, err)
+				assert.True(t, records[0].Skipped)
+				assert.Equal(t, subject, records[0].Subject)
+				assert.Equal(t, test.transport.Operation(), records[0].Operation)
+			} else {
+				assert.ErrorIs(t, err, ErrUnauthorized)
+				assert.False(t, records[0].Skipped)
+			}
+		})
+	}
+}
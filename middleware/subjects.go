@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/tx7do/kratos-authz/engine"
+	"github.com/tx7do/kratos-authz/engine/audit"
+)
+
+// DefaultMaxConcurrency bounds the subjects checked concurrently.
+const DefaultMaxConcurrency = 8
+
+// SubjectsMode defines how the AuthClaims.Subjects are checked.
+type SubjectsMode int
+
+const (
+	// AnyOf allows the request when one of the subjects is allowed.
+	AnyOf SubjectsMode = iota
+	// AllOf allows the request when every subject is allowed.
+	AllOf
+	// Batched checks every subject at once with ProjectsAuthorized, for the engines accepting
+	// several subjects natively, such as OPA. The request is allowed when any of the subjects is,
+	// the requests without a project are checked as with AnyOf.
+	Batched
+)
+
+// ErrorPolicy defines how the failing subject checks are handled.
+type ErrorPolicy int
+
+const (
+	// FailClosed fails the request with the error of the first failing subject check. With AnyOf
+	// the errors are only returned when none of the subjects is allowed.
+	FailClosed ErrorPolicy = iota
+	// SkipErroringSubjects ignores the failing subjects, as if they were not in the claims.
+	SkipErroringSubjects
+)
+
+type subjectResult struct {
+	allowed bool
+	err     error
+}
+
+// checkSubjects checks the subjects according to the SubjectsMode.
+func (o *options) checkSubjects(ctx context.Context, authorizer engine.Authorizer, subjects engine.Subjects, action engine.Action, resource engine.Resource, project engine.Project) error {
+	if o.subjectsMode != Batched {
+		return o.checkEach(ctx, o.subjectsMode, authorizer, subjects, action, resource, project)
+	}
+	if project == "" {
+		// ProjectsAuthorized has no project to check, and the engines do not read the empty one
+		// as their wildcard: any of the subjects is checked with IsAuthorized instead
+		return o.checkEach(ctx, AnyOf, authorizer, subjects, action, resource, project)
+	}
+	return o.checkBatched(ctx, authorizer, subjects, action, resource, project)
+}
+
+// checkEach checks the subjects concurrently, cancelling the remaining checks as soon as the outcome is known.
+func (o *options) checkEach(ctx context.Context, mode SubjectsMode, authorizer engine.Authorizer, subjects engine.Subjects, action engine.Action, resource engine.Resource, project engine.Project) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		decided   bool
+		outcome   error
+		anyPassed bool
+		firstErr  error
+	)
+
+	sem := make(chan struct{}, max(o.maxConcurrency, 1))
+	for _, subject := range subjects {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			allowed, err := o.isAuthorized(ctx, authorizer, subject, action, resource, project)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if decided {
+				return
+			}
+			if err != nil {
+				o.log.Errorf("authz middleware: authorization failed for subject %s, action %s, resource %s, project %s: %v",
+					subject, action, resource, project, err)
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+			if result, ok := o.decisive(mode, subjectResult{allowed: allowed, err: err}); ok {
+				decided, outcome = true, result
+				cancel()
+				return
+			}
+			anyPassed = anyPassed || (err == nil && allowed)
+		}()
+	}
+	wg.Wait()
+
+	if decided {
+		return outcome
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	switch {
+	case mode == AnyOf && anyPassed:
+		return nil
+	case mode == AnyOf && firstErr != nil && o.errorPolicy == FailClosed:
+		// none of the subjects is allowed, the failing ones might have been
+		return firstErr
+	case mode == AllOf && (o.errorPolicy == FailClosed || anyPassed):
+		return nil
+	}
+	return ErrUnauthorized
+}
+
+// decisive reports whether the result of a single subject settles the outcome of the request.
+func (o *options) decisive(mode SubjectsMode, r subjectResult) (error, bool) {
+	switch mode {
+	case AnyOf:
+		// an allowed subject settles the request whatever the other subjects fail with
+		if r.err == nil && r.allowed {
+			return nil, true
+		}
+	case AllOf:
+		if r.err != nil {
+			if o.errorPolicy == FailClosed {
+				return r.err, true
+			}
+			return nil, false
+		}
+		if !r.allowed {
+			return ErrUnauthorized, true
+		}
+	}
+	return nil, false
+}
+
+// checkBatched checks every subject at once, the request is allowed when the project is authorized.
+// With SkipErroringSubjects a failing batch is checked again subject by subject, as the failing
+// subjects are not known.
+func (o *options) checkBatched(ctx context.Context, authorizer engine.Authorizer, subjects engine.Subjects, action engine.Action, resource engine.Resource, project engine.Project) error {
+	start := time.Now()
+	projects, err := authorizer.ProjectsAuthorized(ctx, subjects, action, resource, engine.Projects{project})
+	allowed := err == nil && slices.Contains(projects, project)
+
+	if o.auditor != nil {
+		var decision *engine.Decision
+		if err == nil {
+			if allowed {
+				decision = engine.MakeAllowDecision(authorizer.Name())
+			} else {
+				decision = engine.MakeDenyDecision(authorizer.Name(), "project not authorized")
+			}
+		}
+		record := audit.NewRecord(ctx, engine.MakeRequest("", action, resource, project), decision, err, time.Since(start))
+		record.Subjects = subjects
+		o.audit(ctx, record)
+	}
+
+	if err != nil {
+		o.log.Errorf("authz middleware: authorization failed for subjects %v, action %s, resource %s, project %s: %v",
+			subjects, action, resource, project, err)
+		if o.errorPolicy == FailClosed {
+			return err
+		}
+		return o.checkEach(ctx, AnyOf, authorizer, subjects, action, resource, project)
+	}
+	if !allowed {
+		return ErrUnauthorized
+	}
+	return nil
+}
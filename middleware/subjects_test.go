@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-authz/engine"
+	"github.com/tx7do/kratos-authz/engine/audit"
+	"github.com/tx7do/kratos-authz/engine/noop"
+)
+
+var errSubject = errors.New("subject check failed")
+
+// subjectAuthorizer allows the subjects set to true, and fails for the subjects set to false.
+type subjectAuthorizer struct {
+	engine.Authorizer
+	subjects map[engine.Subject]bool
+
+	calls    atomic.Int32
+	inFlight atomic.Int32
+	maxSeen  atomic.Int32
+}
+
+func (a *subjectAuthorizer) IsAuthorized(_ context.Context, subject engine.Subject, _ engine.Action, _ engine.Resource, _ engine.Project) (bool, error) {
+	a.calls.Add(1)
+	n := a.inFlight.Add(1)
+	defer a.inFlight.Add(-1)
+	for {
+		seen := a.maxSeen.Load()
+		if n <= seen || a.maxSeen.CompareAndSwap(seen, n) {
+			break
+		}
+	}
+
+	allowed, ok := a.subjects[subject]
+	if ok && !allowed {
+		return false, errSubject
+	}
+	return allowed, nil
+}
+
+func (a *subjectAuthorizer) ProjectsAuthorized(_ context.Context, subjects engine.Subjects, _ engine.Action, _ engine.Resource, projects engine.Projects) (engine.Projects, error) {
+	a.calls.Add(1)
+	for _, subject := range subjects {
+		if allowed, ok := a.subjects[subject]; ok && !allowed {
+			return nil, errSubject
+		}
+		if a.subjects[subject] {
+			return projects, nil
+		}
+	}
+	return engine.Projects{}, nil
+}
+
+func TestServer_Subjects(t *testing.T) {
+	noopAuthorizer, _ := noop.NewEngine(t.Context())
+
+	cases := map[string]struct {
+		subjects []string
+		project  engine.Project
+		opts     []Option
+		err      error
+	}{
+		"any of allowed":                 {subjects: []string{"bobo", "admin"}},
+		"any of denied":                  {subjects: []string{"bobo", "alice"}, err: ErrUnauthorized},
+		"any of error allowed":           {subjects: []string{"broken", "admin"}},
+		"any of error fails closed":      {subjects: []string{"broken", "bobo"}, err: errSubject},
+		"any of error skipped":           {subjects: []string{"broken", "admin"}, opts: []Option{WithErrorPolicy(SkipErroringSubjects)}},
+		"any of only errors skipped":     {subjects: []string{"broken"}, opts: []Option{WithErrorPolicy(SkipErroringSubjects)}, err: ErrUnauthorized},
+		"all of allowed":                 {subjects: []string{"admin", "root"}, opts: []Option{WithSubjectsMode(AllOf)}},
+		"all of denied":                  {subjects: []string{"admin", "bobo"}, opts: []Option{WithSubjectsMode(AllOf)}, err: ErrUnauthorized},
+		"all of error fails closed":      {subjects: []string{"admin", "broken"}, opts: []Option{WithSubjectsMode(AllOf)}, err: errSubject},
+		"all of error skipped":           {subjects: []string{"admin", "broken"}, opts: []Option{WithSubjectsMode(AllOf), WithErrorPolicy(SkipErroringSubjects)}},
+		"all of only errors skipped":     {subjects: []string{"broken"}, opts: []Option{WithSubjectsMode(AllOf), WithErrorPolicy(SkipErroringSubjects)}, err: ErrUnauthorized},
+		"batched allowed":                {subjects: []string{"bobo", "admin"}, project: "project1", opts: []Option{WithSubjectsMode(Batched)}},
+		"batched denied":                 {subjects: []string{"bobo", "alice"}, project: "project1", opts: []Option{WithSubjectsMode(Batched)}, err: ErrUnauthorized},
+		"batched error fails closed":     {subjects: []string{"broken", "admin"}, project: "project1", opts: []Option{WithSubjectsMode(Batched)}, err: errSubject},
+		"batched error skipped":          {subjects: []string{"broken", "admin"}, project: "project1", opts: []Option{WithSubjectsMode(Batched), WithErrorPolicy(SkipErroringSubjects)}},
+		"batched only errors skipped":    {subjects: []string{"broken"}, project: "project1", opts: []Option{WithSubjectsMode(Batched), WithErrorPolicy(SkipErroringSubjects)}, err: ErrUnauthorized},
+		"batched without project":        {subjects: []string{"bobo", "admin"}, opts: []Option{WithSubjectsMode(Batched)}},
+		"batched without project denied": {subjects: []string{"bobo", "alice"}, opts: []Option{WithSubjectsMode(Batched)}, err: ErrUnauthorized},
+		"sequential any of allowed":      {subjects: []string{"bobo", "alice", "admin"}, opts: []Option{WithMaxConcurrency(1)}},
+		"sequential all of error":        {subjects: []string{"admin", "broken", "bobo"}, opts: []Option{WithSubjectsMode(AllOf), WithMaxConcurrency(1)}, err: errSubject},
+		"sequential any of skip errors":  {subjects: []string{"broken", "bobo", "admin"}, opts: []Option{WithMaxConcurrency(1), WithErrorPolicy(SkipErroringSubjects)}},
+		"sequential any of error":        {subjects: []string{"broken", "bobo", "admin"}, opts: []Option{WithMaxConcurrency(1)}},
+		"sequential any of allow first":  {subjects: []string{"admin", "broken"}, opts: []Option{WithMaxConcurrency(1)}},
+	}
+
+	for descr, tc := range cases {
+		t.Run(descr, func(t *testing.T) {
+			authorizer := &subjectAuthorizer{
+				Authorizer: noopAuthorizer,
+				subjects:   map[engine.Subject]bool{"admin": true, "root": true, "broken": false},
+			}
+			handler := Server(authorizer, tc.opts...)(func(ctx context.Context, req interface{}) (interface{}, error) {
+				return "reply", nil
+			})
+
+			subjects := tc.subjects
+			claims := &engine.AuthClaims{Subjects: &subjects, Action: &resourceAction, Resource: &resourceName}
+			if tc.project != "" {
+				claims.Project = &tc.project
+			}
+			ctx := engine.ContextWithAuthClaims(t.Context(), claims)
+
+			_, err := handler(ctx, nil)
+			if tc.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.err)
+			}
+		})
+	}
+}
+
+var (
+	resourceAction = engine.Action("GET")
+	resourceName   = engine.Resource("/api/users")
+)
+
+func TestServer_SubjectsConcurrency(t *testing.T) {
+	noopAuthorizer, _ := noop.NewEngine(t.Context())
+	authorizer := &subjectAuthorizer{Authorizer: noopAuthorizer}
+
+	handler := Server(authorizer, WithMaxConcurrency(2))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "reply", nil
+	})
+
+	subjects := []string{"s1", "s2", "s3", "s4", "s5", "s6", "s7", "s8"}
+	ctx := engine.ContextWithAuthClaims(t.Context(), &engine.AuthClaims{Subjects: &subjects, Action: &resourceAction, Resource: &resourceName})
+
+	_, err := handler(ctx, nil)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	assert.EqualValues(t, len(subjects), authorizer.calls.Load())
+	assert.LessOrEqual(t, authorizer.maxSeen.Load(), int32(2))
+}
+
+func TestServer_SubjectsBatchedAudit(t *testing.T) {
+	noopAuthorizer, _ := noop.NewEngine(t.Context())
+	authorizer := &subjectAuthorizer{Authorizer: noopAuthorizer, subjects: map[engine.Subject]bool{"admin": true}}
+	ring := audit.NewRingBuffer(10)
+
+	handler := Server(authorizer, WithSubjectsMode(Batched), WithAuditor(ring))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "reply", nil
+	})
+
+	subjects := []string{"bobo", "admin"}
+	project := engine.Project("project1")
+	ctx := engine.ContextWithAuthClaims(t.Context(), &engine.AuthClaims{Subjects: &subjects, Action: &resourceAction, Resource: &resourceName, Project: &project})
+
+	_, err := handler(ctx, nil)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, authorizer.calls.Load())
+
+	records := ring.Records()
+	assert.Len(t, records, 1)
+	assert.Equal(t, engine.MakeSubjects("bobo", "admin"), records[0].Subjects)
+	assert.Equal(t, engine.EffectAllow, records[0].Effect)
+}